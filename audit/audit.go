@@ -0,0 +1,226 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package audit implements an append-only, hash chained log of the
+// transactions produced by the signer.
+//
+// Each line of the log is a record holding an entry and the hash of the
+// entry's exact bytes. Every entry includes the hash of the record before it,
+// so modifying, reordering, or removing any record breaks the chain of every
+// record after it. Truncating the log can only be detected by comparing its
+// head hash against a copy recorded elsewhere.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+
+	"github.com/StephenButtolph/avalanche-tooling/inspect"
+)
+
+// maxRecordSize bounds the length of a single line of the log
+const maxRecordSize = 64 * 1024 * 1024
+
+var (
+	errHashMismatch     = errors.New("record hash doesn't match its entry")
+	errBrokenChain      = errors.New("entry doesn't reference the previous record")
+	errWrongSequence    = errors.New("entry has the wrong sequence number")
+	errUnexpectedHead   = errors.New("log head doesn't match the expected hash")
+	errTrailingGarbage  = errors.New("log doesn't end with a newline")
+	errMissingEntryData = errors.New("record is missing its entry")
+)
+
+// Entry describes one signed transaction
+type Entry struct {
+	// Sequence is the index of the entry in the log, starting at 0
+	Sequence uint64 `json:"sequence"`
+	// PrevHash is the hash of the previous record, or ids.Empty for the first
+	PrevHash ids.ID    `json:"prevHash"`
+	Time     time.Time `json:"time"`
+	// UnsignedTxID is the hash of the bytes that were signed
+	UnsignedTxID ids.ID `json:"unsignedTxID"`
+	// SignedTxID is the ID the transaction will have once issued, or
+	// ids.Empty if the signatures didn't complete the transaction
+	SignedTxID ids.ID `json:"signedTxID"`
+	// Signers are the addresses that signed the transaction
+	Signers []ids.ShortID    `json:"signers"`
+	Summary *inspect.Summary `json:"summary"`
+}
+
+type record struct {
+	Hash  ids.ID          `json:"hash"`
+	Entry json.RawMessage `json:"entry"`
+}
+
+// Log appends entries to an audit log file
+type Log struct {
+	file     *os.File
+	head     ids.ID
+	sequence uint64
+}
+
+// Open verifies the audit log at [path], creating it if it doesn't exist, and
+// prepares it for appending.
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	numEntries, head, err := verify(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("audit log %q: %w", path, err)
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &Log{
+		file:     file,
+		head:     head,
+		sequence: numEntries,
+	}, nil
+}
+
+// Append populates the sequence number, previous hash, and time of [entry] and
+// durably appends it to the log.
+func (l *Log) Append(entry Entry) error {
+	entry.Sequence = l.sequence
+	entry.PrevHash = l.head
+	entry.Time = time.Now().UTC()
+
+	entryBytes, err := json.Marshal(&entry)
+	if err != nil {
+		return err
+	}
+	r := record{
+		Hash:  hashing.ComputeHash256Array(entryBytes),
+		Entry: entryBytes,
+	}
+	recordBytes, err := json.Marshal(&r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := l.file.Write(append(recordBytes, '\n')); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.head = r.Hash
+	l.sequence++
+	return nil
+}
+
+// Head returns the hash of the last record in the log
+func (l *Log) Head() ids.ID { return l.head }
+
+// Close closes the log file
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// Verify checks that the hash chain of the audit log at [path] is intact. If
+// [expectedHead] isn't ids.Empty, the hash of the last record must equal it.
+// The number of entries and the hash of the last record are returned.
+func Verify(path string, expectedHead ids.ID) (uint64, ids.ID, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, ids.Empty, err
+	}
+	defer file.Close()
+
+	numEntries, head, err := verify(file)
+	if err != nil {
+		return numEntries, head, err
+	}
+	if expectedHead != ids.Empty && head != expectedHead {
+		return numEntries, head, fmt.Errorf("%w: expected %s but got %s", errUnexpectedHead, expectedHead, head)
+	}
+	return numEntries, head, nil
+}
+
+func verify(r io.Reader) (uint64, ids.ID, error) {
+	var (
+		reader     = bufio.NewReader(r)
+		head       ids.ID
+		numEntries uint64
+	)
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			return numEntries, head, nil
+		}
+		if err != nil {
+			return numEntries, head, fmt.Errorf("line %d: %w", numEntries+1, err)
+		}
+
+		head, err = verifyRecord(line, numEntries, head)
+		if err != nil {
+			return numEntries, head, fmt.Errorf("line %d: %w", numEntries+1, err)
+		}
+		numEntries++
+	}
+}
+
+// verifyRecord checks that [line] holds the entry with [sequence] chained to
+// [prevHash] and returns the record's hash.
+func verifyRecord(line []byte, sequence uint64, prevHash ids.ID) (ids.ID, error) {
+	r := record{}
+	if err := json.Unmarshal(line, &r); err != nil {
+		return ids.Empty, err
+	}
+	if len(r.Entry) == 0 {
+		return ids.Empty, errMissingEntryData
+	}
+	if hash := hashing.ComputeHash256Array(r.Entry); hash != r.Hash {
+		return ids.Empty, errHashMismatch
+	}
+
+	entry := Entry{}
+	if err := json.Unmarshal(r.Entry, &entry); err != nil {
+		return ids.Empty, err
+	}
+	if entry.Sequence != sequence {
+		return ids.Empty, fmt.Errorf("%w: expected %d but got %d", errWrongSequence, sequence, entry.Sequence)
+	}
+	if entry.PrevHash != prevHash {
+		return ids.Empty, errBrokenChain
+	}
+	return r.Hash, nil
+}
+
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(line)+len(chunk) > maxRecordSize {
+			return nil, fmt.Errorf("record exceeds %d bytes", maxRecordSize)
+		}
+		line = append(line, chunk...)
+		switch err {
+		case nil:
+			return bytes.TrimSuffix(line, []byte{'\n'}), nil
+		case bufio.ErrBufferFull:
+			continue
+		case io.EOF:
+			if len(line) > 0 {
+				return nil, errTrailingGarbage
+			}
+			return nil, io.EOF
+		default:
+			return nil, err
+		}
+	}
+}
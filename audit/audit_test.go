@@ -0,0 +1,186 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+)
+
+const numTestEntries = 3
+
+// newTestLog returns the lines of a log of [numTestEntries] entries along with
+// its head
+func newTestLog(t *testing.T) ([][]byte, ids.ID) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < numTestEntries; i++ {
+		err := l.Append(Entry{
+			UnsignedTxID: ids.GenerateTestID(),
+			Signers:      []ids.ShortID{{1}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	head := l.Head()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	logBytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.SplitAfter(logBytes, []byte{'\n'})[:numTestEntries], head
+}
+
+// changeSigner returns [line] with the signers of its entry replaced. If
+// [rehash] is true, the record's hash is updated to match.
+func changeSigner(t *testing.T, line []byte, rehash bool) []byte {
+	t.Helper()
+
+	r := record{}
+	if err := json.Unmarshal(line, &r); err != nil {
+		t.Fatal(err)
+	}
+	entry := Entry{}
+	if err := json.Unmarshal(r.Entry, &entry); err != nil {
+		t.Fatal(err)
+	}
+	entry.Signers = []ids.ShortID{{2}}
+
+	var err error
+	r.Entry, err = json.Marshal(&entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rehash {
+		r.Hash = hashing.ComputeHash256Array(r.Entry)
+	}
+	recordBytes, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(recordBytes, '\n')
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name               string
+		tamper             func(t *testing.T, lines [][]byte) [][]byte
+		checkHead          bool
+		expectedNumEntries uint64
+		expectedErr        error
+	}{
+		{
+			name:               "intact",
+			expectedNumEntries: numTestEntries,
+		},
+		{
+			name:               "intact with head",
+			checkHead:          true,
+			expectedNumEntries: numTestEntries,
+		},
+		{
+			name: "tampered entry",
+			tamper: func(t *testing.T, lines [][]byte) [][]byte {
+				lines[1] = changeSigner(t, lines[1], false)
+				return lines
+			},
+			expectedNumEntries: 1,
+			expectedErr:        errHashMismatch,
+		},
+		{
+			name: "tampered entry with its hash",
+			tamper: func(t *testing.T, lines [][]byte) [][]byte {
+				lines[1] = changeSigner(t, lines[1], true)
+				return lines
+			},
+			expectedNumEntries: 2,
+			expectedErr:        errBrokenChain,
+		},
+		{
+			name: "tampered last entry with its hash",
+			tamper: func(t *testing.T, lines [][]byte) [][]byte {
+				lines[2] = changeSigner(t, lines[2], true)
+				return lines
+			},
+			checkHead:          true,
+			expectedNumEntries: numTestEntries,
+			expectedErr:        errUnexpectedHead,
+		},
+		{
+			name: "reordered",
+			tamper: func(_ *testing.T, lines [][]byte) [][]byte {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			expectedNumEntries: 1,
+			expectedErr:        errWrongSequence,
+		},
+		{
+			name: "removed entry",
+			tamper: func(_ *testing.T, lines [][]byte) [][]byte {
+				return [][]byte{lines[0], lines[2]}
+			},
+			expectedNumEntries: 1,
+			expectedErr:        errWrongSequence,
+		},
+		{
+			name: "truncated record",
+			tamper: func(_ *testing.T, lines [][]byte) [][]byte {
+				lines[2] = lines[2][:len(lines[2])/2]
+				return lines
+			},
+			expectedNumEntries: 2,
+			expectedErr:        errTrailingGarbage,
+		},
+		{
+			name: "truncated entries",
+			tamper: func(_ *testing.T, lines [][]byte) [][]byte {
+				return lines[:2]
+			},
+			checkHead:          true,
+			expectedNumEntries: 2,
+			expectedErr:        errUnexpectedHead,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines, head := newTestLog(t)
+			if test.tamper != nil {
+				lines = test.tamper(t, lines)
+			}
+			path := filepath.Join(t.TempDir(), "audit.log")
+			if err := os.WriteFile(path, bytes.Join(lines, nil), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			expectedHead := ids.Empty
+			if test.checkHead {
+				expectedHead = head
+			}
+			numEntries, _, err := Verify(path, expectedHead)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected %v, got %v", test.expectedErr, err)
+			}
+			if numEntries != test.expectedNumEntries {
+				t.Fatalf("expected %d verified entries, got %d", test.expectedNumEntries, numEntries)
+			}
+		})
+	}
+}
@@ -6,11 +6,18 @@ package checksum
 import (
 	"bufio"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 
-	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/StephenButtolph/avalanche-tooling/txio"
 )
 
+// AddChecksum writes every raw hex line in [inFilePath] to [outFilePath] as
+// checksummed hex. Lines are streamed, so lines of any length are handled in
+// bounded memory. The output is written atomically, so [outFilePath] is left
+// untouched if an error occurs.
 func AddChecksum(inFilePath, outFilePath string) error {
 	inFile, err := os.Open(inFilePath)
 	if err != nil {
@@ -18,38 +25,100 @@ func AddChecksum(inFilePath, outFilePath string) error {
 	}
 	defer inFile.Close()
 
-	outFile, err := os.Create(outFilePath)
+	outFile, err := txio.CreateAtomic(outFilePath)
 	if err != nil {
 		return err
 	}
-	defer outFile.Close()
+	defer outFile.Abort()
 
-	scanner := bufio.NewScanner(inFile)
-	const maxCapacity = 500_000_000
-	buf := make([]byte, maxCapacity)
-	scanner.Buffer(buf, maxCapacity)
-	for scanner.Scan() {
-		rawHex := scanner.Text()
-		bytes, err := hex.DecodeString(rawHex)
+	if err := streamChecksums(inFile, outFile); err != nil {
+		return err
+	}
+	return outFile.Commit()
+}
+
+// Convert re-encodes every record of [inFilePath] from [inEncoding] to
+// [outEncoding] and writes them to [outFilePath]. Up to [workers] records are
+// converted concurrently while preserving their order.
+//
+// Only raw hex converted to checksummed hex by a single worker is streamed, as
+// by AddChecksum. Otherwise every record must fit in memory, and a record
+// larger than txio.MaxRecordSize is reported as such.
+func Convert(inFilePath, outFilePath string, inEncoding, outEncoding txio.Encoding, workers int) error {
+	if inEncoding == txio.RawHex && outEncoding == txio.CheckedHex && workers <= 1 {
+		return AddChecksum(inFilePath, outFilePath)
+	}
+
+	err := txio.TransformParallel(inFilePath, outFilePath, inEncoding, outEncoding, workers, func(record []byte) ([]byte, error) {
+		return record, nil
+	})
+	if errors.Is(err, txio.ErrRecordTooLarge) {
+		return fmt.Errorf("%w, only %s records converted to %s by a single worker are streamed without a size limit",
+			err,
+			txio.RawHex,
+			txio.CheckedHex,
+		)
+	}
+	return err
+}
+
+// VerifyChecksum checks the checksum of every checksummed hex or CB58 line in
+// [inFilePath] and returns the line numbers, starting from 1, of the lines
+// that are corrupt. If [strippedFilePath] is non-empty and every line is valid,
+// the raw hex of every line is written to it. The stripped file is never
+// written if any line is corrupt, so that it is never missing transactions.
+func VerifyChecksum(inFilePath, strippedFilePath string) ([]int, error) {
+	inFile, err := os.Open(inFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer inFile.Close()
+
+	var outFile *txio.AtomicFile
+	if strippedFilePath != "" {
+		outFile, err = txio.CreateAtomic(strippedFilePath)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		defer outFile.Abort()
+	}
 
-		checkedHex, err := formatting.EncodeWithChecksum(formatting.Hex, bytes)
-		if err != nil {
-			return err
+	var corruptLines []int
+	scanner := bufio.NewScanner(inFile)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), txio.MaxRecordSize)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		bytes, encoding, err := txio.Auto.Decode(line)
+		if err != nil || (encoding != txio.CheckedHex && encoding != txio.CB58) {
+			corruptLines = append(corruptLines, lineNumber)
+			continue
+		}
+
+		if outFile == nil || len(corruptLines) > 0 {
+			continue
 		}
 
-		_, err = outFile.WriteString(checkedHex)
+		_, err = outFile.WriteString(hex.EncodeToString(bytes))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		_, err = outFile.WriteString("\n")
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if outFile == nil || len(corruptLines) > 0 {
+		return corruptLines, nil
+	}
+	return corruptLines, outFile.Commit()
 }
@@ -0,0 +1,166 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package checksum
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+const (
+	streamBufferSize = 1 << 20
+	checksumLen      = 4
+)
+
+var (
+	errOddLength   = errors.New("odd number of hex characters")
+	errInvalidChar = errors.New("invalid hex character")
+)
+
+// streamChecksums copies every raw hex line of [r] to [w] as checksummed hex.
+// Lines are processed in chunks, so lines of any length are handled in
+// bounded memory. Empty lines are skipped.
+func streamChecksums(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReaderSize(r, streamBufferSize)
+	writer := bufio.NewWriterSize(w, streamBufferSize)
+	line := lineEncoder{
+		w:       writer,
+		hasher:  sha256.New(),
+		decoded: make([]byte, 0, streamBufferSize/2),
+		encoded: make([]byte, streamBufferSize),
+	}
+	for lineNumber := 1; ; lineNumber++ {
+		done, err := line.copy(reader)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if done {
+			return writer.Flush()
+		}
+	}
+}
+
+// lineEncoder converts a single raw hex line into checksummed hex. Its buffers
+// are reused across lines.
+type lineEncoder struct {
+	w      *bufio.Writer
+	hasher hash.Hash
+
+	// started is true once the 0x prefix of the current line has been written
+	started bool
+	// high holds the first nibble of a byte split across chunks
+	high    byte
+	hasHigh bool
+	// hasCR is true if the last character was a carriage return, which may
+	// only end the line
+	hasCR bool
+
+	decoded []byte
+	encoded []byte
+}
+
+// copy converts the next line of [r]. It returns true once [r] is exhausted.
+func (l *lineEncoder) copy(r *bufio.Reader) (bool, error) {
+	l.hasher.Reset()
+	l.started = false
+	l.hasHigh = false
+	l.hasCR = false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		switch {
+		case err == nil:
+			if err := l.write(chunk); err != nil {
+				return false, err
+			}
+			return false, l.finish()
+		case errors.Is(err, bufio.ErrBufferFull):
+			if err := l.write(chunk); err != nil {
+				return false, err
+			}
+		case errors.Is(err, io.EOF):
+			if err := l.write(chunk); err != nil {
+				return false, err
+			}
+			return true, l.finish()
+		default:
+			return false, err
+		}
+	}
+}
+
+func (l *lineEncoder) write(chunk []byte) error {
+	l.decoded = l.decoded[:0]
+	for _, c := range chunk {
+		if l.hasCR && c != '\n' {
+			return fmt.Errorf("%w: %q", errInvalidChar, '\r')
+		}
+
+		var nibble byte
+		switch {
+		case c == '\r':
+			l.hasCR = true
+			continue
+		case c == '\n':
+			continue
+		case '0' <= c && c <= '9':
+			nibble = c - '0'
+		case 'a' <= c && c <= 'f':
+			nibble = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			nibble = c - 'A' + 10
+		default:
+			return fmt.Errorf("%w: %q", errInvalidChar, c)
+		}
+
+		if !l.hasHigh {
+			l.high = nibble
+			l.hasHigh = true
+			continue
+		}
+		l.decoded = append(l.decoded, l.high<<4|nibble)
+		l.hasHigh = false
+	}
+	return l.emit(l.decoded)
+}
+
+// emit writes [b] as hex and includes it in the line's checksum.
+func (l *lineEncoder) emit(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if !l.started {
+		if _, err := l.w.WriteString("0x"); err != nil {
+			return err
+		}
+		l.started = true
+	}
+
+	_, _ = l.hasher.Write(b)
+	n := hex.Encode(l.encoded, b)
+	_, err := l.w.Write(l.encoded[:n])
+	return err
+}
+
+// finish writes the checksum of the current line. Nothing is written for empty
+// lines.
+func (l *lineEncoder) finish() error {
+	if l.hasHigh {
+		return errOddLength
+	}
+	if !l.started {
+		return nil
+	}
+
+	sum := l.hasher.Sum(nil)
+	n := hex.Encode(l.encoded, sum[len(sum)-checksumLen:])
+	if _, err := l.w.Write(l.encoded[:n]); err != nil {
+		return err
+	}
+	return l.w.WriteByte('\n')
+}
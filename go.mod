@@ -2,4 +2,10 @@ module github.com/StephenButtolph/avalanche-tooling
 
 go 1.16
 
-require github.com/ava-labs/avalanchego v1.5.2
+require (
+	github.com/ava-labs/avalanchego v1.5.2
+	github.com/decred/dcrd/dcrec/secp256k1/v3 v3.0.0-20200627015759-01fd2de07837
+	github.com/mr-tron/base58 v1.2.0
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
+	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1
+)
@@ -0,0 +1,237 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package hd derives addresses from a BIP32 extended public key, so that new
+// addresses can be handed out without access to their secret keys.
+package hd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/mr-tron/base58"
+)
+
+const (
+	// HardenedIndex is the first index of a hardened child. Hardened children
+	// can't be derived from a public key.
+	HardenedIndex = 1 << 31
+
+	// ChangeBranch is the BIP44 branch, below an account, of change addresses
+	ChangeBranch = 1
+
+	serializedLen = 78
+	checksumLen   = 4
+	chainCodeLen  = 32
+)
+
+var (
+	errInvalidChecksum = errors.New("invalid checksum")
+	errInvalidLength   = errors.New("invalid length")
+	errHardened        = errors.New("can't derive a hardened child from a public key")
+	errInvalidChild    = errors.New("invalid child")
+)
+
+// ExtendedKey is a BIP32 extended public key
+type ExtendedKey struct {
+	pubKey    *secp256k1.PublicKey
+	chainCode []byte
+}
+
+// ParseExtendedKey parses a base58 encoded extended public key, such as the
+// "xpub..." of an account exported by a wallet
+func ParseExtendedKey(s string) (*ExtendedKey, error) {
+	b, err := base58.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode extended key: %w", err)
+	}
+	if len(b) != serializedLen+checksumLen {
+		return nil, fmt.Errorf("%w: extended key has %d bytes, expected %d", errInvalidLength, len(b), serializedLen+checksumLen)
+	}
+
+	payload, checksum := b[:serializedLen], b[serializedLen:]
+	if !bytes.Equal(doubleSHA256(payload)[:checksumLen], checksum) {
+		return nil, fmt.Errorf("%w of extended key", errInvalidChecksum)
+	}
+
+	// version (4) | depth (1) | parent fingerprint (4) | child number (4) |
+	// chain code (32) | public key (33)
+	chainCode := payload[13 : 13+chainCodeLen]
+	pubKey, err := secp256k1.ParsePubKey(payload[13+chainCodeLen:])
+	if err != nil {
+		return nil, fmt.Errorf("extended key doesn't contain a public key: %w", err)
+	}
+	return &ExtendedKey{
+		pubKey:    pubKey,
+		chainCode: append([]byte(nil), chainCode...),
+	}, nil
+}
+
+// Child returns the non-hardened child of [k] at [index]
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if index >= HardenedIndex {
+		return nil, errHardened
+	}
+
+	data := make([]byte, 0, 37)
+	data = append(data, k.pubKey.SerializeCompressed()...)
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	_, _ = mac.Write(data)
+	sum := mac.Sum(nil)
+	il, ir := sum[:32], sum[32:]
+
+	var tweak secp256k1.ModNScalar
+	if overflow := tweak.SetByteSlice(il); overflow || tweak.IsZero() {
+		return nil, fmt.Errorf("%w at index %d", errInvalidChild, index)
+	}
+
+	var tweakPoint, parentPoint, childPoint secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&tweak, &tweakPoint)
+	k.pubKey.AsJacobian(&parentPoint)
+	secp256k1.AddNonConst(&tweakPoint, &parentPoint, &childPoint)
+	if (childPoint.X.IsZero() && childPoint.Y.IsZero()) || childPoint.Z.IsZero() {
+		return nil, fmt.Errorf("%w at index %d", errInvalidChild, index)
+	}
+	childPoint.ToAffine()
+
+	return &ExtendedKey{
+		pubKey:    secp256k1.NewPublicKey(&childPoint.X, &childPoint.Y),
+		chainCode: ir,
+	}, nil
+}
+
+// Address returns the address of [k]'s public key
+func (k *ExtendedKey) Address() ids.ShortID {
+	return ids.ShortID(hashing.ComputeHash160Array(hashing.ComputeHash256(k.pubKey.SerializeCompressed())))
+}
+
+// Deriver hands out the addresses of consecutive children of a key. An
+// address that is handed out is reserved until it's either used, once a
+// transaction paying it is issued, or released to be handed out again.
+type Deriver struct {
+	lock sync.Mutex
+	key  *ExtendedKey
+	// nextIndex is the lowest index that hasn't been handed out
+	nextIndex uint32
+	// released are the indices that were handed out and then released
+	released []uint32
+	// reserved are the indices of the addresses that are handed out
+	reserved map[ids.ShortID]uint32
+	// nextUnused is one past the highest used index
+	nextUnused uint32
+}
+
+// NewDeriver returns a deriver whose first address is that of the child of
+// [key] at [startIndex]
+func NewDeriver(key *ExtendedKey, startIndex uint32) *Deriver {
+	return &Deriver{
+		key:        key,
+		nextIndex:  startIndex,
+		reserved:   make(map[ids.ShortID]uint32),
+		nextUnused: startIndex,
+	}
+}
+
+// NextAddress reserves and returns the address of the lowest child that isn't
+// reserved or used. Indices of invalid children are skipped, as specified by
+// BIP32.
+func (d *Deriver) NextAddress() (ids.ShortID, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.released) > 0 {
+		sort.Slice(d.released, func(i, j int) bool { return d.released[i] < d.released[j] })
+		index := d.released[0]
+		d.released = d.released[1:]
+		return d.reserve(index)
+	}
+
+	for d.nextIndex < HardenedIndex {
+		index := d.nextIndex
+		d.nextIndex++
+
+		addr, err := d.reserve(index)
+		if errors.Is(err, errInvalidChild) {
+			continue
+		}
+		return addr, err
+	}
+	return ids.ShortID{}, errHardened
+}
+
+func (d *Deriver) reserve(index uint32) (ids.ShortID, error) {
+	child, err := d.key.Child(index)
+	if err != nil {
+		return ids.ShortID{}, err
+	}
+	addr := child.Address()
+	d.reserved[addr] = index
+	return addr, nil
+}
+
+// Use records that [addr], if it was handed out, has been paid by an issued
+// transaction
+func (d *Deriver) Use(addr ids.ShortID) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	index, ok := d.reserved[addr]
+	if !ok {
+		return
+	}
+	delete(d.reserved, addr)
+	if index >= d.nextUnused {
+		d.nextUnused = index + 1
+	}
+}
+
+// Release allows [addr], if it was handed out and not used, to be handed out
+// again
+func (d *Deriver) Release(addr ids.ShortID) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	index, ok := d.reserved[addr]
+	if !ok {
+		return
+	}
+	delete(d.reserved, addr)
+	d.released = append(d.released, index)
+}
+
+// NextUnused returns one past the highest index whose address was used, or
+// the start index if none were. Starting the next deriver there neither
+// reuses addresses nor leaves a gap after the used ones.
+func (d *Deriver) NextUnused() uint32 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.nextUnused
+}
+
+// NextIndex returns the lowest index that hasn't been handed out
+func (d *Deriver) NextIndex() uint32 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.nextIndex
+}
+
+func doubleSHA256(b []byte) []byte {
+	first := sha256.Sum256(b)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
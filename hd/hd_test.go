@@ -0,0 +1,143 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package hd
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestChildVectors checks the public derivation steps of BIP32 test vectors 1
+// and 2. Each step derives the non-hardened [index] child of [parent].
+func TestChildVectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		parent   string
+		index    uint32
+		expected string
+	}{
+		{
+			name:     "vector 1 m/0H/1",
+			parent:   "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+			index:    1,
+			expected: "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+		},
+		{
+			name:     "vector 1 m/0H/1/2H/2",
+			parent:   "xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
+			index:    2,
+			expected: "xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+		},
+		{
+			name:     "vector 1 m/0H/1/2H/2/1000000000",
+			parent:   "xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+			index:    1000000000,
+			expected: "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		},
+		{
+			name:     "vector 2 m/0",
+			parent:   "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB",
+			index:    0,
+			expected: "xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH",
+		},
+		{
+			name:     "vector 2 m/0/2147483647H/1",
+			parent:   "xpub6ASAVgeehLbnwdqV6UKMHVzgqAG8Gr6riv3Fxxpj8ksbH9ebxaEyBLZ85ySDhKiLDBrQSARLq1uNRts8RuJiHjaDMBU4Zn9h8LZNnBC5y4a",
+			index:    1,
+			expected: "xpub6DF8uhdarytz3FWdA8TvFSvvAh8dP3283MY7p2V4SeE2wyWmG5mg5EwVvmdMVCQcoNJxGoWaU9DCWh89LojfZ537wTfunKau47EL2dhHKon",
+		},
+		{
+			name:     "vector 2 m/0/2147483647H/1/2147483646H/2",
+			parent:   "xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL",
+			index:    2,
+			expected: "xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parent, err := ParseExtendedKey(test.parent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := ParseExtendedKey(test.expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			child, err := parent.Child(test.index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !child.pubKey.IsEqual(expected.pubKey) {
+				t.Fatalf("expected public key %x, got %x", expected.pubKey.SerializeCompressed(), child.pubKey.SerializeCompressed())
+			}
+			if !bytes.Equal(child.chainCode, expected.chainCode) {
+				t.Fatalf("expected chain code %x, got %x", expected.chainCode, child.chainCode)
+			}
+		})
+	}
+}
+
+func TestChildHardened(t *testing.T) {
+	key, err := ParseExtendedKey("xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := key.Child(HardenedIndex); err != errHardened {
+		t.Fatalf("expected %v, got %v", errHardened, err)
+	}
+}
+
+func TestParseExtendedKeyChecksum(t *testing.T) {
+	// The last character of vector 2's master key is changed
+	_, err := ParseExtendedKey("xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduC")
+	if err == nil {
+		t.Fatal("expected a corrupted key to be rejected")
+	}
+}
+
+func TestDeriverOnlyUsesIssuedIndices(t *testing.T) {
+	key, err := ParseExtendedKey("xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDeriver(key, 5)
+
+	first, err := d.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := d.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatal("reserved addresses were handed out twice")
+	}
+	if next := d.NextUnused(); next != 5 {
+		t.Fatalf("expected no indices to be used, got next unused index %d", next)
+	}
+
+	// Releasing the first address hands it out again
+	d.Release(first)
+	again, err := d.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Fatalf("expected the released address %s, got %s", first, again)
+	}
+
+	d.Use(second)
+	if next := d.NextUnused(); next != 7 {
+		t.Fatalf("expected next unused index 7, got %d", next)
+	}
+	d.Use(first)
+	if next := d.NextUnused(); next != 7 {
+		t.Fatalf("expected next unused index 7, got %d", next)
+	}
+	if next := d.NextIndex(); next != 7 {
+		t.Fatalf("expected next index 7, got %d", next)
+	}
+}
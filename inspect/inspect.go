@@ -0,0 +1,79 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package inspect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+// Format selects how transactions are displayed
+type Format uint8
+
+const (
+	// Text displays a human readable summary of each transaction
+	Text Format = iota
+	// JSON displays each transaction as an indented JSON object
+	JSON
+	// TextAndJSON displays both the summary and the JSON object
+	TextAndJSON
+)
+
+// Inspect decodes every unsigned transaction in [inFilePath], which is encoded
+// with [encoding], and writes a description of each of them to [w] in the
+// requested [format]. The transactions are decoded as [chain] transactions, or
+// the chain of each transaction is detected if [chain] is txs.Unknown. No
+// network access is required.
+func Inspect(inFilePath string, chain txs.Chain, encoding txio.Encoding, format Format, w io.Writer) error {
+	inFile, err := os.Open(inFilePath)
+	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+
+	reader := txio.NewReader(inFile, encoding)
+	for {
+		unsignedTxBytes, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		unsignedTx, err := txs.Parse(chain, unsignedTxBytes)
+		if err != nil {
+			return fmt.Errorf("%s: %w", reader.Position(), err)
+		}
+
+		summary, err := Describe(unsignedTx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", reader.Position(), err)
+		}
+
+		if _, err := fmt.Fprintf(w, "# %s\n", reader.Position()); err != nil {
+			return err
+		}
+		if format == Text || format == TextAndJSON {
+			if err := summary.WriteText(w); err != nil {
+				return err
+			}
+		}
+		if format == JSON || format == TextAndJSON {
+			summaryJSON, err := json.MarshalIndent(summary, "", "  ")
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", summaryJSON); err != nil {
+				return err
+			}
+		}
+	}
+}
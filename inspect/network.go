@@ -0,0 +1,80 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package inspect
+
+import (
+	"sync"
+
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+)
+
+// network holds the information needed to display values of a network in a
+// human readable format. It is derived from the network's genesis so that it
+// is available offline.
+type network struct {
+	id  uint32
+	hrp string
+	// avaxAssetID is the empty ID if the network's genesis isn't known
+	avaxAssetID  ids.ID
+	chainAliases map[ids.ID]string
+}
+
+var (
+	networksLock sync.Mutex
+	networks     = map[uint32]*network{}
+)
+
+// getNetwork returns the information of [networkID]. Only the P-chain is known
+// for networks without a built in genesis.
+func getNetwork(networkID uint32) *network {
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	if n, ok := networks[networkID]; ok {
+		return n
+	}
+
+	n := &network{
+		id:  networkID,
+		hrp: constants.GetHRP(networkID),
+		chainAliases: map[ids.ID]string{
+			constants.PlatformChainID: "P",
+		},
+	}
+	networks[networkID] = n
+
+	switch networkID {
+	case constants.MainnetID, constants.FujiID, constants.LocalID:
+	default:
+		return n
+	}
+
+	genesisBytes, avaxAssetID, err := genesis.Genesis(networkID, "")
+	if err != nil {
+		return n
+	}
+	n.avaxAssetID = avaxAssetID
+
+	_, chainAliases, err := genesis.Aliases(genesisBytes)
+	if err != nil {
+		return n
+	}
+	for chainID, aliases := range chainAliases {
+		if len(aliases) > 0 {
+			n.chainAliases[chainID] = aliases[0]
+		}
+	}
+	return n
+}
+
+// chainAlias returns the primary alias of [chainID], or [fallback] if the
+// chain isn't known.
+func (n *network) chainAlias(chainID ids.ID, fallback string) string {
+	if alias, ok := n.chainAliases[chainID]; ok {
+		return alias
+	}
+	return fallback
+}
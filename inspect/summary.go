@@ -0,0 +1,422 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/utils/math"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/components/verify"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+const (
+	KindOutput   = "output"
+	KindExported = "exported"
+	KindStake    = "stake"
+)
+
+var errProducesMoreThanConsumed = errors.New("transaction produces more than it consumes")
+
+// Summary is a human readable description of an unsigned transaction
+type Summary struct {
+	Chain            txs.Chain  `json:"chain"`
+	Type             string     `json:"type"`
+	NetworkID        uint32     `json:"networkID"`
+	BlockchainID     ids.ID     `json:"blockchainID"`
+	SourceChain      *ids.ID    `json:"sourceChain,omitempty"`
+	DestinationChain *ids.ID    `json:"destinationChain,omitempty"`
+	Memo             string     `json:"memo,omitempty"`
+	Inputs           []Input    `json:"inputs"`
+	Outputs          []Output   `json:"outputs"`
+	Validator        *Validator `json:"validator,omitempty"`
+	RewardsOwner     *Owners    `json:"rewardsOwner,omitempty"`
+	SubnetOwner      *Owners    `json:"subnetOwner,omitempty"`
+	// Fees are the amounts of each asset that are consumed but not produced
+	Fees []Amount `json:"fees"`
+
+	network *network
+}
+
+// Amount is a quantity of an asset. AVAX is only populated for amounts of the
+// network's AVAX asset.
+type Amount struct {
+	AssetID ids.ID `json:"assetID"`
+	Amount  uint64 `json:"amount"`
+	AVAX    string `json:"avax,omitempty"`
+}
+
+type Input struct {
+	Amount
+	TxID        ids.ID `json:"txID"`
+	OutputIndex uint32 `json:"outputIndex"`
+	// StakeableLocktime is only populated for stakeable locked inputs
+	StakeableLocktime uint64   `json:"stakeableLocktime,omitempty"`
+	SigIndices        []uint32 `json:"sigIndices"`
+	Imported          bool     `json:"imported,omitempty"`
+	// EVMAddress and Nonce are only populated for the C-chain account inputs
+	// of atomic exports
+	EVMAddress string `json:"evmAddress,omitempty"`
+	Nonce      uint64 `json:"nonce,omitempty"`
+}
+
+type Output struct {
+	Amount
+	Kind string `json:"kind"`
+	// StakeableLocktime is only populated for stakeable locked outputs
+	StakeableLocktime uint64 `json:"stakeableLocktime,omitempty"`
+	// EVMAddress is only populated for the C-chain account outputs of atomic
+	// imports, which have no owners
+	EVMAddress string `json:"evmAddress,omitempty"`
+	Owners
+}
+
+type Owners struct {
+	Locktime  uint64   `json:"locktime"`
+	Threshold uint32   `json:"threshold"`
+	Addresses []string `json:"addresses"`
+}
+
+type Validator struct {
+	NodeID   string  `json:"nodeID"`
+	Start    uint64  `json:"start"`
+	End      uint64  `json:"end"`
+	Weight   uint64  `json:"weight"`
+	SubnetID *ids.ID `json:"subnetID,omitempty"`
+	// Shares is only populated for primary network validators
+	Shares *uint32 `json:"shares,omitempty"`
+}
+
+// Describe returns a human readable description of [unsignedTx].
+func Describe(unsignedTx txs.UnsignedTx) (*Summary, error) {
+	tx := unsignedTx.Tx()
+	s := &Summary{
+		Chain: unsignedTx.Chain(),
+		Type:  strings.TrimPrefix(reflect.TypeOf(tx).Elem().Name(), "Unsigned"),
+	}
+
+	var (
+		base     *avax.BaseTx
+		imported []*avax.TransferableInput
+		exported []*avax.TransferableOutput
+		staked   []*avax.TransferableOutput
+		evmIns   []txs.EVMInput
+		evmOuts  []txs.EVMOutput
+	)
+	switch tx := tx.(type) {
+	case *platformvm.UnsignedAddValidatorTx:
+		base = &tx.BaseTx.BaseTx
+		staked = tx.Stake
+		s.Validator = &Validator{
+			NodeID: tx.Validator.NodeID.PrefixedString(constants.NodeIDPrefix),
+			Start:  tx.Validator.Start,
+			End:    tx.Validator.End,
+			Weight: tx.Validator.Wght,
+			Shares: &tx.Shares,
+		}
+		s.RewardsOwner = s.owners(tx.RewardsOwner)
+	case *platformvm.UnsignedAddDelegatorTx:
+		base = &tx.BaseTx.BaseTx
+		staked = tx.Stake
+		s.Validator = &Validator{
+			NodeID: tx.Validator.NodeID.PrefixedString(constants.NodeIDPrefix),
+			Start:  tx.Validator.Start,
+			End:    tx.Validator.End,
+			Weight: tx.Validator.Wght,
+		}
+		s.RewardsOwner = s.owners(tx.RewardsOwner)
+	case *platformvm.UnsignedAddSubnetValidatorTx:
+		base = &tx.BaseTx.BaseTx
+		s.Validator = &Validator{
+			NodeID:   tx.Validator.NodeID.PrefixedString(constants.NodeIDPrefix),
+			Start:    tx.Validator.Start,
+			End:      tx.Validator.End,
+			Weight:   tx.Validator.Wght,
+			SubnetID: &tx.Validator.Subnet,
+		}
+	case *platformvm.UnsignedCreateSubnetTx:
+		base = &tx.BaseTx.BaseTx
+		s.SubnetOwner = s.owners(tx.Owner)
+	case *platformvm.UnsignedCreateChainTx:
+		base = &tx.BaseTx.BaseTx
+	case *platformvm.UnsignedImportTx:
+		base = &tx.BaseTx.BaseTx
+		imported = tx.ImportedInputs
+		s.SourceChain = &tx.SourceChain
+	case *platformvm.UnsignedExportTx:
+		base = &tx.BaseTx.BaseTx
+		exported = tx.ExportedOutputs
+		s.DestinationChain = &tx.DestinationChain
+	case *avm.BaseTx:
+		base = &tx.BaseTx
+	case *avm.CreateAssetTx:
+		base = &tx.BaseTx.BaseTx
+	case *avm.OperationTx:
+		base = &tx.BaseTx.BaseTx
+	case *avm.ImportTx:
+		base = &tx.BaseTx.BaseTx
+		imported = tx.ImportedIns
+		s.SourceChain = &tx.SourceChain
+	case *avm.ExportTx:
+		base = &tx.BaseTx.BaseTx
+		exported = tx.ExportedOuts
+		s.DestinationChain = &tx.DestinationChain
+	case *txs.UnsignedImportTx:
+		base = &avax.BaseTx{
+			NetworkID:    tx.NetworkID,
+			BlockchainID: tx.BlockchainID,
+		}
+		imported = tx.ImportedInputs
+		evmOuts = tx.Outs
+		s.SourceChain = &tx.SourceChain
+	case *txs.UnsignedExportTx:
+		base = &avax.BaseTx{
+			NetworkID:    tx.NetworkID,
+			BlockchainID: tx.BlockchainID,
+		}
+		evmIns = tx.Ins
+		exported = tx.ExportedOutputs
+		s.DestinationChain = &tx.DestinationChain
+	default:
+		return nil, fmt.Errorf("can't describe transaction of type %T", tx)
+	}
+
+	s.NetworkID = base.NetworkID
+	s.BlockchainID = base.BlockchainID
+	s.network = getNetwork(base.NetworkID)
+	if len(base.Memo) > 0 {
+		s.Memo = fmt.Sprintf("0x%x", []byte(base.Memo))
+	}
+
+	consumed := make(map[ids.ID]uint64)
+	produced := make(map[ids.ID]uint64)
+	for _, in := range base.Ins {
+		if err := s.addInput(in, false, consumed); err != nil {
+			return nil, err
+		}
+	}
+	for _, in := range imported {
+		if err := s.addInput(in, true, consumed); err != nil {
+			return nil, err
+		}
+	}
+	for _, in := range evmIns {
+		if err := s.addEVMInput(in, consumed); err != nil {
+			return nil, err
+		}
+	}
+
+	chainAlias := s.network.chainAlias(base.BlockchainID, s.Chain.String())
+	for _, out := range base.Outs {
+		if err := s.addOutput(out, KindOutput, chainAlias, produced); err != nil {
+			return nil, err
+		}
+	}
+	for _, out := range evmOuts {
+		if err := s.addEVMOutput(out, produced); err != nil {
+			return nil, err
+		}
+	}
+	if s.DestinationChain != nil {
+		destinationAlias := s.network.chainAlias(*s.DestinationChain, s.DestinationChain.String())
+		for _, out := range exported {
+			if err := s.addOutput(out, KindExported, destinationAlias, produced); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, out := range staked {
+		if err := s.addOutput(out, KindStake, chainAlias, produced); err != nil {
+			return nil, err
+		}
+	}
+
+	assetIDs := make([]ids.ID, 0, len(consumed))
+	for assetID := range consumed {
+		assetIDs = append(assetIDs, assetID)
+	}
+	for assetID := range produced {
+		if _, ok := consumed[assetID]; !ok {
+			return nil, fmt.Errorf("%w: asset %s is never consumed", errProducesMoreThanConsumed, assetID)
+		}
+	}
+	ids.SortIDs(assetIDs)
+
+	s.Fees = []Amount{}
+	for _, assetID := range assetIDs {
+		consumedAmount := consumed[assetID]
+		producedAmount := produced[assetID]
+		if producedAmount > consumedAmount {
+			return nil, fmt.Errorf("%w: asset %s consumes %d but produces %d",
+				errProducesMoreThanConsumed,
+				assetID,
+				consumedAmount,
+				producedAmount,
+			)
+		}
+		if fee := consumedAmount - producedAmount; fee > 0 {
+			s.Fees = append(s.Fees, s.Amount(assetID, fee))
+		}
+	}
+	return s, nil
+}
+
+// AVAXAssetID returns the ID of the AVAX asset of the transaction's network,
+// or ids.Empty if the network's genesis isn't known.
+func (s *Summary) AVAXAssetID() ids.ID {
+	return s.network.avaxAssetID
+}
+
+func (s *Summary) addInput(in *avax.TransferableInput, imported bool, consumed map[ids.ID]uint64) error {
+	assetID := in.AssetID()
+	input := Input{
+		Amount:      s.Amount(assetID, in.In.Amount()),
+		TxID:        in.TxID,
+		OutputIndex: in.OutputIndex,
+		Imported:    imported,
+	}
+
+	transferIn := in.In
+	if lockedIn, ok := transferIn.(*platformvm.StakeableLockIn); ok {
+		input.StakeableLocktime = lockedIn.Locktime
+		transferIn = lockedIn.TransferableIn
+	}
+	if secpIn, ok := transferIn.(*secp256k1fx.TransferInput); ok {
+		input.SigIndices = secpIn.SigIndices
+	}
+	s.Inputs = append(s.Inputs, input)
+
+	newConsumed, err := math.Add64(consumed[assetID], input.Amount.Amount)
+	if err != nil {
+		return err
+	}
+	consumed[assetID] = newConsumed
+	return nil
+}
+
+func (s *Summary) addEVMInput(in txs.EVMInput, consumed map[ids.ID]uint64) error {
+	input := Input{
+		Amount:     s.Amount(in.AssetID, in.Amount),
+		EVMAddress: fmt.Sprintf("0x%x", in.Address),
+		Nonce:      in.Nonce,
+	}
+	s.Inputs = append(s.Inputs, input)
+
+	newConsumed, err := math.Add64(consumed[in.AssetID], in.Amount)
+	if err != nil {
+		return err
+	}
+	consumed[in.AssetID] = newConsumed
+	return nil
+}
+
+func (s *Summary) addEVMOutput(out txs.EVMOutput, produced map[ids.ID]uint64) error {
+	output := Output{
+		Amount:     s.Amount(out.AssetID, out.Amount),
+		Kind:       KindOutput,
+		EVMAddress: fmt.Sprintf("0x%x", out.Address),
+	}
+	s.Outputs = append(s.Outputs, output)
+
+	newProduced, err := math.Add64(produced[out.AssetID], out.Amount)
+	if err != nil {
+		return err
+	}
+	produced[out.AssetID] = newProduced
+	return nil
+}
+
+func (s *Summary) addOutput(out *avax.TransferableOutput, kind, chainAlias string, produced map[ids.ID]uint64) error {
+	assetID := out.AssetID()
+	output := Output{
+		Amount: s.Amount(assetID, out.Out.Amount()),
+		Kind:   kind,
+	}
+
+	transferOut := out.Out
+	if lockedOut, ok := transferOut.(*platformvm.StakeableLockOut); ok {
+		output.StakeableLocktime = lockedOut.Locktime
+		transferOut = lockedOut.TransferableOut
+	}
+	if secpOut, ok := transferOut.(*secp256k1fx.TransferOutput); ok {
+		owners, err := s.formatOwners(&secpOut.OutputOwners, chainAlias)
+		if err != nil {
+			return err
+		}
+		output.Owners = *owners
+	}
+	s.Outputs = append(s.Outputs, output)
+
+	newProduced, err := math.Add64(produced[assetID], output.Amount.Amount)
+	if err != nil {
+		return err
+	}
+	produced[assetID] = newProduced
+	return nil
+}
+
+// owners describes P-chain owners, such as a rewards owner. Addresses that
+// can't be formatted are displayed in their raw form.
+func (s *Summary) owners(ownersIntf verify.Verifiable) *Owners {
+	owners, ok := ownersIntf.(*secp256k1fx.OutputOwners)
+	if !ok {
+		return nil
+	}
+	formatted, err := s.formatOwners(owners, "P")
+	if err != nil {
+		formatted = &Owners{
+			Locktime:  owners.Locktime,
+			Threshold: owners.Threshold,
+		}
+		for _, addr := range owners.Addrs {
+			formatted.Addresses = append(formatted.Addresses, addr.String())
+		}
+	}
+	return formatted
+}
+
+func (s *Summary) formatOwners(owners *secp256k1fx.OutputOwners, chainAlias string) (*Owners, error) {
+	formatted := &Owners{
+		Locktime:  owners.Locktime,
+		Threshold: owners.Threshold,
+		Addresses: make([]string, len(owners.Addrs)),
+	}
+	for i, addr := range owners.Addrs {
+		addrStr, err := formatting.FormatAddress(chainAlias, s.network.hrp, addr[:])
+		if err != nil {
+			return nil, err
+		}
+		formatted.Addresses[i] = addrStr
+	}
+	return formatted, nil
+}
+
+// Amount returns [amount] of [assetID], which is also formatted in AVAX if
+// [assetID] is the AVAX asset of the transaction's network.
+func (s *Summary) Amount(assetID ids.ID, amount uint64) Amount {
+	a := Amount{
+		AssetID: assetID,
+		Amount:  amount,
+	}
+	if assetID == s.network.avaxAssetID {
+		a.AVAX = formatAVAX(amount)
+	}
+	return a
+}
+
+func formatAVAX(amount uint64) string {
+	return fmt.Sprintf("%d.%09d", amount/units.Avax, amount%units.Avax)
+}
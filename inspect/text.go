@@ -0,0 +1,121 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package inspect
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+)
+
+// WriteText writes a multi-line, human readable, description of the
+// transaction to [w].
+func (s *Summary) WriteText(w io.Writer) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s-chain %s on network %d (%s)\n", s.Chain, s.Type, s.NetworkID, constants.NetworkName(s.NetworkID))
+	fmt.Fprintf(b, "  blockchain:        %s%s\n", s.BlockchainID, s.chainSuffix(s.BlockchainID))
+	if s.SourceChain != nil {
+		fmt.Fprintf(b, "  source chain:      %s%s\n", *s.SourceChain, s.chainSuffix(*s.SourceChain))
+	}
+	if s.DestinationChain != nil {
+		fmt.Fprintf(b, "  destination chain: %s%s\n", *s.DestinationChain, s.chainSuffix(*s.DestinationChain))
+	}
+	if s.Memo != "" {
+		fmt.Fprintf(b, "  memo:              %s\n", s.Memo)
+	}
+	if v := s.Validator; v != nil {
+		weight := fmt.Sprintf("%d", v.Weight)
+		if v.SubnetID == nil {
+			// primary network weights are denominated in nAVAX
+			weight = formatAVAX(v.Weight) + " AVAX"
+		}
+		fmt.Fprintf(b, "  validator:         %s weight %s from %s to %s\n",
+			v.NodeID,
+			weight,
+			formatTime(v.Start),
+			formatTime(v.End),
+		)
+		if v.SubnetID != nil {
+			fmt.Fprintf(b, "  subnet:            %s\n", *v.SubnetID)
+		}
+		if v.Shares != nil {
+			fmt.Fprintf(b, "  delegation fee:    %.4f%%\n", float64(*v.Shares)/10_000)
+		}
+	}
+	if s.RewardsOwner != nil {
+		fmt.Fprintf(b, "  rewards owner:     %s\n", formatOwners(s.RewardsOwner))
+	}
+	if s.SubnetOwner != nil {
+		fmt.Fprintf(b, "  subnet owner:      %s\n", formatOwners(s.SubnetOwner))
+	}
+
+	fmt.Fprintf(b, "  inputs:\n")
+	for i, in := range s.Inputs {
+		if in.EVMAddress != "" {
+			fmt.Fprintf(b, "    [%d] %s from %s with nonce %d\n", i, in.Amount, in.EVMAddress, in.Nonce)
+			continue
+		}
+		fmt.Fprintf(b, "    [%d] %s from %s:%d with signers %v", i, in.Amount, in.TxID, in.OutputIndex, in.SigIndices)
+		if in.Imported {
+			fmt.Fprintf(b, " (imported)")
+		}
+		if in.StakeableLocktime != 0 {
+			fmt.Fprintf(b, " (stakeable until %s)", formatTime(in.StakeableLocktime))
+		}
+		fmt.Fprintln(b)
+	}
+
+	fmt.Fprintf(b, "  outputs:\n")
+	for i, out := range s.Outputs {
+		if out.EVMAddress != "" {
+			fmt.Fprintf(b, "    [%d] %s %s to %s\n", i, out.Kind, out.Amount, out.EVMAddress)
+			continue
+		}
+		fmt.Fprintf(b, "    [%d] %s %s to %s", i, out.Kind, out.Amount, formatOwners(&out.Owners))
+		if out.StakeableLocktime != 0 {
+			fmt.Fprintf(b, " (stakeable until %s)", formatTime(out.StakeableLocktime))
+		}
+		fmt.Fprintln(b)
+	}
+
+	fmt.Fprintf(b, "  fees:\n")
+	for _, fee := range s.Fees {
+		fmt.Fprintf(b, "    %s\n", fee)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// chainSuffix returns the alias of [chainID] formatted to follow the chain ID,
+// or the empty string if the chain isn't known.
+func (s *Summary) chainSuffix(chainID ids.ID) string {
+	if alias := s.network.chainAlias(chainID, ""); alias != "" {
+		return " (" + alias + ")"
+	}
+	return ""
+}
+
+func (a Amount) String() string {
+	if a.AVAX != "" {
+		return a.AVAX + " AVAX"
+	}
+	return fmt.Sprintf("%d of %s", a.Amount, a.AssetID)
+}
+
+func formatOwners(o *Owners) string {
+	str := fmt.Sprintf("%d of %v", o.Threshold, o.Addresses)
+	if o.Locktime != 0 {
+		str += fmt.Sprintf(" locked until %s", formatTime(o.Locktime))
+	}
+	return str
+}
+
+func formatTime(unix uint64) string {
+	return time.Unix(int64(unix), 0).UTC().Format(time.RFC3339)
+}
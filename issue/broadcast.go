@@ -0,0 +1,136 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+var (
+	errNoChainClient = errors.New("no client for chain")
+	errNotBroadcast  = errors.New("transactions weren't accepted")
+)
+
+// BroadcastResult is the outcome of broadcasting one transaction
+type BroadcastResult struct {
+	// Index of the transaction in the file, starting at 1
+	Index  int            `json:"index"`
+	Chain  txs.Chain      `json:"chain"`
+	TxID   ids.ID         `json:"txID"`
+	Status choices.Status `json:"status"`
+	// Skipped is set if the transaction was already accepted, so it wasn't
+	// issued again
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Broadcast issues every signed transaction in [inFilePath], which is encoded
+// with [encoding], to its chain and waits for it to be decided. The chain of
+// each transaction is [chain], or is detected if [chain] is txs.Unknown.
+//
+// One JSON encoded BroadcastResult is written to [report] per transaction.
+// Transactions that are already accepted are skipped, so a broadcast that
+// didn't succeed can be rerun. Every transaction is attempted even if an
+// earlier one fails, and an error is returned if any wasn't accepted.
+func Broadcast(
+	inFilePath string,
+	chain txs.Chain,
+	encoding txio.Encoding,
+	clients map[txs.Chain]ChainClient,
+	report io.Writer,
+) error {
+	inFile, err := os.Open(inFilePath)
+	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+
+	var (
+		reader    = txio.NewReader(inFile, encoding)
+		encoder   = json.NewEncoder(report)
+		numTxs    int
+		numFailed int
+	)
+	for {
+		txBytes, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		result := broadcastTx(txBytes, chain, clients)
+		result.Index = reader.Index()
+		if err := encoder.Encode(&result); err != nil {
+			return err
+		}
+
+		numTxs++
+		if result.Status != choices.Accepted {
+			numFailed++
+		}
+	}
+	if numFailed > 0 {
+		return fmt.Errorf("%w: %d of %d", errNotBroadcast, numFailed, numTxs)
+	}
+	return nil
+}
+
+func broadcastTx(txBytes []byte, chain txs.Chain, clients map[txs.Chain]ChainClient) BroadcastResult {
+	result := BroadcastResult{
+		TxID: hashing.ComputeHash256Array(txBytes),
+	}
+
+	unsignedTx, err := txs.ParseSigned(chain, txBytes)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Chain = unsignedTx.Chain()
+
+	client, ok := clients[result.Chain]
+	if !ok {
+		result.Error = fmt.Sprintf("%s %s", errNoChainClient, result.Chain)
+		return result
+	}
+
+	status, err := client.Status(result.TxID)
+	if err != nil {
+		result.Error = fmt.Sprintf("couldn't fetch status: %s", err)
+		return result
+	}
+	switch status {
+	case choices.Accepted:
+		result.Status = status
+		result.Skipped = true
+		return result
+	case choices.Rejected:
+		result.Status = status
+		result.Error = "transaction was already rejected"
+		return result
+	case choices.Unknown:
+		if _, err := client.IssueTx(txBytes); err != nil {
+			result.Error = fmt.Sprintf("couldn't issue: %s", err)
+			return result
+		}
+	}
+
+	result.Status, err = client.Confirm(result.TxID)
+	if err != nil {
+		result.Error = fmt.Sprintf("couldn't confirm: %s", err)
+	}
+	return result
+}
@@ -0,0 +1,256 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+)
+
+var (
+	errNoChangeAddrs     = errors.New("no change addresses")
+	errUnknownSpendOwner = errors.New("can't determine the owners of the spent UTXOs")
+	errMixedSpendOwners  = errors.New("spent UTXOs have different owners")
+
+	_ ChangePolicy = &FixedChange{}
+	_ ChangePolicy = &RoundRobinChange{}
+	_ ChangePolicy = &FreshChange{}
+	_ ChangePolicy = SpendingOwnerChange{}
+
+	_ ChangeTracker = &FreshChange{}
+)
+
+// ChangePolicy chooses the owners of the change returned by each transaction.
+// It may be called by transactions that are being built concurrently.
+//
+// Change that the signer can't spend right away, because it's sent to other
+// addresses or is locked, can't fund the transactions that follow it.
+type ChangePolicy interface {
+	// ChangeOwners returns the owners of the change of a transaction that
+	// spends [consumed]
+	ChangeOwners(consumed []*avax.UTXO) (*secp256k1fx.OutputOwners, error)
+
+	// MaxAddrs returns the most addresses that change may be owned by, so
+	// that room is left for it in each transaction
+	MaxAddrs() int
+}
+
+// ChangeTracker is a ChangePolicy whose owners are only used up once a
+// transaction paying them is issued
+type ChangeTracker interface {
+	ChangePolicy
+
+	// Issued is called once [tx], whose change owners were chosen by the
+	// policy, has been issued
+	Issued(tx *Tx)
+
+	// Discard is called with change owners chosen by the policy that won't be
+	// issued, so that they may be chosen again
+	Discard(owners *secp256k1fx.OutputOwners)
+}
+
+// FixedChange sends all change to the same owners, which may require
+// multiple signatures and be locked until a time
+type FixedChange struct {
+	Owners secp256k1fx.OutputOwners
+}
+
+// NewFixedChange returns a policy sending change to [addrs], [threshold] of
+// which must sign to spend it after [locktime]
+func NewFixedChange(addrs []ids.ShortID, threshold uint32, locktime uint64) (*FixedChange, error) {
+	if len(addrs) == 0 {
+		return nil, errNoChangeAddrs
+	}
+	if threshold == 0 || int(threshold) > len(addrs) {
+		return nil, fmt.Errorf("%w: %d of %d", errInvalidThreshold, threshold, len(addrs))
+	}
+	owners := secp256k1fx.OutputOwners{
+		Locktime:  locktime,
+		Threshold: threshold,
+		Addrs:     append([]ids.ShortID(nil), addrs...),
+	}
+	owners.Sort()
+	if err := owners.Verify(); err != nil {
+		return nil, err
+	}
+	return &FixedChange{Owners: owners}, nil
+}
+
+func (f *FixedChange) ChangeOwners([]*avax.UTXO) (*secp256k1fx.OutputOwners, error) {
+	return &f.Owners, nil
+}
+
+func (f *FixedChange) MaxAddrs() int { return len(f.Owners.Addrs) }
+
+// RoundRobinChange sends the change of each transaction to the next of its
+// addresses, such as the addresses of the signer, so that change doesn't
+// pile up on one of them
+type RoundRobinChange struct {
+	lock  sync.Mutex
+	addrs []ids.ShortID
+	next  int
+}
+
+func NewRoundRobinChange(addrs []ids.ShortID) (*RoundRobinChange, error) {
+	if len(addrs) == 0 {
+		return nil, errNoChangeAddrs
+	}
+	return &RoundRobinChange{addrs: addrs}, nil
+}
+
+func (r *RoundRobinChange) ChangeOwners([]*avax.UTXO) (*secp256k1fx.OutputOwners, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	addr := r.addrs[r.next]
+	r.next = (r.next + 1) % len(r.addrs)
+	return singleOwner(addr), nil
+}
+
+func (*RoundRobinChange) MaxAddrs() int { return 1 }
+
+// AddressDeriver hands out a new address every time it's called. The
+// *hd.Deriver implements it.
+type AddressDeriver interface {
+	// NextAddress reserves and returns an address that hasn't been used
+	NextAddress() (ids.ShortID, error)
+	// Use records that [addr] has been paid by an issued transaction
+	Use(addr ids.ShortID)
+	// Release allows [addr], which wasn't used, to be handed out again
+	Release(addr ids.ShortID)
+}
+
+// FreshChange sends the change of each transaction to a new address, such as
+// one derived from a wallet's extended public key. An address is only used up
+// once a transaction paying it is issued.
+type FreshChange struct {
+	Deriver AddressDeriver
+}
+
+func (f *FreshChange) ChangeOwners([]*avax.UTXO) (*secp256k1fx.OutputOwners, error) {
+	addr, err := f.Deriver.NextAddress()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't derive change address: %w", err)
+	}
+	return singleOwner(addr), nil
+}
+
+func (*FreshChange) MaxAddrs() int { return 1 }
+
+func (f *FreshChange) Issued(tx *Tx) {
+	for _, utxo := range tx.UTXOs {
+		if out, ok := utxo.Out.(*secp256k1fx.TransferOutput); ok && len(out.Addrs) == 1 {
+			f.Deriver.Use(out.Addrs[0])
+		}
+	}
+}
+
+func (f *FreshChange) Discard(owners *secp256k1fx.OutputOwners) {
+	for _, addr := range owners.Addrs {
+		f.Deriver.Release(addr)
+	}
+}
+
+// SpendingOwnerChange returns the change of each transaction to the owners,
+// and threshold, of the UTXOs that it spends. The change isn't locked. A
+// transaction spending UTXOs of different owners isn't built, since its change
+// can't be returned to all of them.
+//
+// The owners aren't known until the transaction is built, so room is only
+// left for change with a single owner. A transaction that turns out to be too
+// large is reported before it is signed.
+type SpendingOwnerChange struct{}
+
+func (SpendingOwnerChange) ChangeOwners(consumed []*avax.UTXO) (*secp256k1fx.OutputOwners, error) {
+	if len(consumed) == 0 {
+		return nil, errUnknownSpendOwner
+	}
+	owners, err := spendOwners(consumed[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, utxo := range consumed[1:] {
+		utxoOwners, err := spendOwners(utxo)
+		if err != nil {
+			return nil, err
+		}
+		if !owners.Equals(utxoOwners) {
+			return nil, fmt.Errorf("%w: %s and %s", errMixedSpendOwners, consumed[0].InputID(), utxo.InputID())
+		}
+	}
+	return owners, nil
+}
+
+func (SpendingOwnerChange) MaxAddrs() int { return 1 }
+
+// spendOwners returns the owners, and threshold, of [utxo] without its locks
+func spendOwners(utxo *avax.UTXO) (*secp256k1fx.OutputOwners, error) {
+	out := utxo.Out
+	if lockedOut, ok := out.(*platformvm.StakeableLockOut); ok {
+		out = lockedOut.TransferableOut
+	}
+	transferOut, ok := out.(*secp256k1fx.TransferOutput)
+	if !ok {
+		return nil, fmt.Errorf("%w: output of type %T", errUnknownSpendOwner, out)
+	}
+	return &secp256k1fx.OutputOwners{
+		Threshold: transferOut.Threshold,
+		Addrs:     transferOut.Addrs,
+	}, nil
+}
+
+// changeOwners returns the owners of the change of a transaction that spends
+// [consumed], as chosen by [policy] or, if it isn't set, the first address of
+// [s]
+func changeOwners(policy ChangePolicy, s signer.Signer, consumed []*avax.UTXO) (*secp256k1fx.OutputOwners, error) {
+	if policy == nil {
+		return singleOwner(s.Addresses()[0]), nil
+	}
+	return policy.ChangeOwners(consumed)
+}
+
+// changeIssued tells [policy], if it tracks its change, that [tx] was issued
+func changeIssued(policy ChangePolicy, tx *Tx) {
+	if tracker, ok := policy.(ChangeTracker); ok {
+		tracker.Issued(tx)
+	}
+}
+
+// discardChange tells [policy], if it tracks its change, that [owners] won't
+// be issued. [owners] may be nil.
+func discardChange(policy ChangePolicy, owners *secp256k1fx.OutputOwners) {
+	if tracker, ok := policy.(ChangeTracker); ok && owners != nil {
+		tracker.Discard(owners)
+	}
+}
+
+// untrackedChange hides whether a policy tracks its change, so that
+// transactions that aren't really issued, such as those of a dry run, don't
+// use it up
+type untrackedChange struct {
+	ChangePolicy
+}
+
+// untracked returns [policy] without its change tracking
+func untracked(policy ChangePolicy) ChangePolicy {
+	if _, ok := policy.(ChangeTracker); ok {
+		return untrackedChange{ChangePolicy: policy}
+	}
+	return policy
+}
+
+func singleOwner(addr ids.ShortID) *secp256k1fx.OutputOwners {
+	return &secp256k1fx.OutputOwners{
+		Threshold: 1,
+		Addrs:     []ids.ShortID{addr},
+	}
+}
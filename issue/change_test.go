@@ -0,0 +1,91 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+)
+
+// newTestOwnedUTXO returns a UTXO owned by [threshold] of [addrs]
+func newTestOwnedUTXO(txID byte, threshold uint32, addrs ...ids.ShortID) *avax.UTXO {
+	return &avax.UTXO{
+		UTXOID: avax.UTXOID{TxID: ids.ID{txID}},
+		Asset:  avax.Asset{ID: testAssetID},
+		Out: &secp256k1fx.TransferOutput{
+			Amt: 1,
+			OutputOwners: secp256k1fx.OutputOwners{
+				Threshold: threshold,
+				Addrs:     addrs,
+			},
+		},
+	}
+}
+
+func TestSpendingOwnerChange(t *testing.T) {
+	otherAddr := ids.ShortID{2}
+	tests := []struct {
+		name           string
+		consumed       []*avax.UTXO
+		expectedOwners *secp256k1fx.OutputOwners
+		expectedErr    error
+	}{
+		{
+			name:           "single owner",
+			consumed:       []*avax.UTXO{newTestUTXO(1, 10, 0), newTestUTXO(2, 20, 0)},
+			expectedOwners: singleOwner(testAddr),
+		},
+		{
+			name:           "locked",
+			consumed:       []*avax.UTXO{newTestUTXO(1, 10, 1), newTestUTXO(2, 20, 0)},
+			expectedOwners: singleOwner(testAddr),
+		},
+		{
+			name: "multisig",
+			consumed: []*avax.UTXO{
+				newTestOwnedUTXO(1, 2, testAddr, otherAddr),
+				newTestOwnedUTXO(2, 2, testAddr, otherAddr),
+			},
+			expectedOwners: &secp256k1fx.OutputOwners{
+				Threshold: 2,
+				Addrs:     []ids.ShortID{testAddr, otherAddr},
+			},
+		},
+		{
+			name:        "different owners",
+			consumed:    []*avax.UTXO{newTestUTXO(1, 10, 0), newTestOwnedUTXO(2, 1, otherAddr)},
+			expectedErr: errMixedSpendOwners,
+		},
+		{
+			name: "different thresholds",
+			consumed: []*avax.UTXO{
+				newTestOwnedUTXO(1, 1, testAddr, otherAddr),
+				newTestOwnedUTXO(2, 2, testAddr, otherAddr),
+			},
+			expectedErr: errMixedSpendOwners,
+		},
+		{
+			name:        "nothing spent",
+			expectedErr: errUnknownSpendOwner,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			owners, err := SpendingOwnerChange{}.ChangeOwners(test.consumed)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected %v, got %v", test.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if !owners.Equals(test.expectedOwners) {
+				t.Fatalf("expected change owners %v, got %v", test.expectedOwners, owners)
+			}
+		})
+	}
+}
@@ -4,23 +4,7 @@
 package issue
 
 import (
-	"github.com/ava-labs/avalanchego/codec"
-	"github.com/ava-labs/avalanchego/vms/avm"
-	"github.com/ava-labs/avalanchego/vms/nftfx"
-	"github.com/ava-labs/avalanchego/vms/propertyfx"
-	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
 )
 
-var c codec.Manager
-
-func init() {
-	gc, _, err := avm.NewCodecs([]avm.Fx{
-		&secp256k1fx.Fx{},
-		&nftfx.Fx{},
-		&propertyfx.Fx{},
-	})
-	if err != nil {
-		panic(err)
-	}
-	c = gc
-}
+var c = txs.XCodec
@@ -0,0 +1,270 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+)
+
+// defaultBranchAndBoundTries is the number of branches BranchAndBound explores
+// if [MaxTries] isn't set
+const defaultBranchAndBoundTries = 100_000
+
+var (
+	errTooManyInputs = errors.New("too many inputs")
+	errNoExactMatch  = errors.New("no inputs exactly match the amount")
+
+	_ CoinSelector = LargestFirst{}
+	_ CoinSelector = SmallestFirst{}
+	_ CoinSelector = FewestInputs{}
+	_ CoinSelector = &BranchAndBound{}
+)
+
+// Coin is a UTXO that the signer is able to spend now
+type Coin struct {
+	UTXO  *avax.UTXO
+	Input avax.TransferableIn
+	// Signers are the addresses that must sign [Input]
+	Signers []ids.ShortID
+}
+
+func (c *Coin) Amount() uint64 { return c.Input.Amount() }
+
+// CoinSelector chooses which coins of a single asset fund a transaction
+type CoinSelector interface {
+	// Select returns coins, from [coins], worth at least [amount]. If
+	// [maxInputs] is positive, at most [maxInputs] coins may be returned.
+	// [coins] is sorted by UTXO ID and may be reordered.
+	Select(coins []*Coin, amount uint64, maxInputs int) ([]*Coin, error)
+}
+
+// coinSelectorNames are the names accepted by ParseCoinSelector
+var coinSelectorNames = []string{
+	"largest-first",
+	"smallest-first",
+	"fewest-inputs",
+	"branch-and-bound",
+}
+
+// ParseCoinSelector returns the coin selector named [name], which is one of
+// "largest-first", "smallest-first", "fewest-inputs", or "branch-and-bound".
+// Branch and bound falls back to largest first if no exact match exists.
+func ParseCoinSelector(name string) (CoinSelector, error) {
+	switch strings.ToLower(name) {
+	case coinSelectorNames[0]:
+		return LargestFirst{}, nil
+	case coinSelectorNames[1]:
+		return SmallestFirst{}, nil
+	case coinSelectorNames[2]:
+		return FewestInputs{}, nil
+	case coinSelectorNames[3]:
+		return &BranchAndBound{Fallback: LargestFirst{}}, nil
+	default:
+		return nil, fmt.Errorf("unknown coin selector %q, expected one of %s", name, strings.Join(coinSelectorNames, ", "))
+	}
+}
+
+// LargestFirst spends the largest coins first, which uses the fewest inputs
+// but leaves dust unspent
+type LargestFirst struct{}
+
+func (LargestFirst) Select(coins []*Coin, amount uint64, maxInputs int) ([]*Coin, error) {
+	sortCoins(coins, true)
+	return takeCoins(coins, amount, maxInputs)
+}
+
+// SmallestFirst spends the smallest coins first, which cleans up dust at the
+// cost of larger transactions. If that would take more than the maximum
+// number of inputs, as many of the smallest coins as possible are topped up
+// with the largest.
+type SmallestFirst struct{}
+
+func (SmallestFirst) Select(coins []*Coin, amount uint64, maxInputs int) ([]*Coin, error) {
+	sortCoins(coins, false)
+	selected, err := takeCoins(coins, amount, maxInputs)
+	if !errors.Is(err, errTooManyInputs) {
+		return selected, err
+	}
+
+	for numSmallest := maxInputs - 1; numSmallest >= 0; numSmallest-- {
+		smallest := coins[:numSmallest]
+		needed := amount - sumCoins(smallest)
+
+		largest := append([]*Coin(nil), coins[numSmallest:]...)
+		sortCoins(largest, true)
+		topUp, err := takeCoins(largest, needed, maxInputs-numSmallest)
+		if err == nil {
+			return append(append([]*Coin(nil), smallest...), topUp...), nil
+		}
+	}
+	return nil, err
+}
+
+// FewestInputs spends as few coins as possible and, of the selections with
+// that many coins, prefers the ones worth the least so that large coins are
+// kept for later.
+type FewestInputs struct{}
+
+func (FewestInputs) Select(coins []*Coin, amount uint64, maxInputs int) ([]*Coin, error) {
+	sortCoins(coins, true)
+	largest, err := takeCoins(coins, amount, maxInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fill each of the slots with the smallest coin that still allows the
+	// largest remaining coins to cover the rest of [amount].
+	var (
+		remaining = append([]*Coin(nil), coins...)
+		selected  = make([]*Coin, 0, len(largest))
+		needed    = amount
+	)
+	for slots := len(largest); slots > 0 && needed > 0; slots-- {
+		largestOthers := sumCoins(remaining[:slots-1])
+		for i := len(remaining) - 1; i >= 0; i-- {
+			coin := remaining[i]
+			// The largest [slots-1] coins other than [coin]
+			others := largestOthers
+			if i < slots-1 {
+				others = addSaturating(others-coin.Amount(), amountAt(remaining, slots-1))
+			}
+			if addSaturating(coin.Amount(), others) < needed {
+				continue
+			}
+
+			selected = append(selected, coin)
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			needed -= minUint64(needed, coin.Amount())
+			break
+		}
+	}
+	if needed > 0 {
+		return largest, nil
+	}
+	return selected, nil
+}
+
+// BranchAndBound searches for coins worth exactly the amount, so that the
+// transaction doesn't need change. If there aren't any, the selection is made
+// by [Fallback], or fails if it isn't set.
+type BranchAndBound struct {
+	// MaxTries is the number of branches explored before giving up. Defaults
+	// to 100,000.
+	MaxTries int
+	Fallback CoinSelector
+}
+
+func (b *BranchAndBound) Select(coins []*Coin, amount uint64, maxInputs int) ([]*Coin, error) {
+	sortCoins(coins, true)
+
+	// remaining[i] is the value of coins[i:]
+	remaining := make([]uint64, len(coins)+1)
+	for i := len(coins) - 1; i >= 0; i-- {
+		remaining[i] = addSaturating(remaining[i+1], coins[i].Amount())
+	}
+
+	tries := b.MaxTries
+	if tries <= 0 {
+		tries = defaultBranchAndBoundTries
+	}
+	var (
+		selected []*Coin
+		search   func(i int, needed uint64) bool
+	)
+	search = func(i int, needed uint64) bool {
+		if needed == 0 {
+			return true
+		}
+		if tries <= 0 || i == len(coins) || remaining[i] < needed || (maxInputs > 0 && len(selected) >= maxInputs) {
+			return false
+		}
+		tries--
+
+		// Include coins[i] if it doesn't overshoot, otherwise skip it
+		if coin := coins[i]; coin.Amount() <= needed {
+			selected = append(selected, coin)
+			if search(i+1, needed-coin.Amount()) {
+				return true
+			}
+			selected = selected[:len(selected)-1]
+		}
+		return search(i+1, needed)
+	}
+	if search(0, amount) {
+		return selected, nil
+	}
+
+	if b.Fallback == nil {
+		return nil, errNoExactMatch
+	}
+	return b.Fallback.Select(coins, amount, maxInputs)
+}
+
+// takeCoins returns the shortest prefix of [coins] worth at least [amount]
+func takeCoins(coins []*Coin, amount uint64, maxInputs int) ([]*Coin, error) {
+	var total uint64
+	for i, coin := range coins {
+		if total >= amount {
+			return coins[:i], nil
+		}
+		if maxInputs > 0 && i >= maxInputs {
+			return nil, fmt.Errorf("%w: spending %d requires more than %d inputs", errTooManyInputs, amount, maxInputs)
+		}
+		total = addSaturating(total, coin.Amount())
+	}
+	if total < amount {
+		return nil, fmt.Errorf("%w: want to spend %d but only have %d", errInsufficientFunds, amount, total)
+	}
+	return coins, nil
+}
+
+// sortCoins sorts [coins] by amount, breaking ties by UTXO ID so that the
+// selection is deterministic
+func sortCoins(coins []*Coin, descending bool) {
+	sort.SliceStable(coins, func(i, j int) bool {
+		amountI, amountJ := coins[i].Amount(), coins[j].Amount()
+		if amountI != amountJ {
+			return (amountI > amountJ) == descending
+		}
+		idI, idJ := coins[i].UTXO.InputID(), coins[j].UTXO.InputID()
+		return bytes.Compare(idI[:], idJ[:]) < 0
+	})
+}
+
+func sumCoins(coins []*Coin) uint64 {
+	var total uint64
+	for _, coin := range coins {
+		total = addSaturating(total, coin.Amount())
+	}
+	return total
+}
+
+func amountAt(coins []*Coin, i int) uint64 {
+	if i < len(coins) {
+		return coins[i].Amount()
+	}
+	return 0
+}
+
+func addSaturating(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,190 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+)
+
+func newTestCoin(txID byte, amount uint64) *Coin {
+	utxo := newTestUTXO(txID, amount, 0)
+	return &Coin{
+		UTXO:    utxo,
+		Input:   &secp256k1fx.TransferInput{Amt: amount},
+		Signers: []ids.ShortID{testAddr},
+	}
+}
+
+// newTestCoins returns coins of 1, 2, 5, 10, and 20 whose transaction IDs
+// start with 1, 2, 3, 4, and 5, in the order of [txIDs]
+func newTestCoins(txIDs []byte) []*Coin {
+	amounts := map[byte]uint64{1: 1, 2: 2, 3: 5, 4: 10, 5: 20}
+	coins := make([]*Coin, len(txIDs))
+	for i, txID := range txIDs {
+		coins[i] = newTestCoin(txID, amounts[txID])
+	}
+	return coins
+}
+
+func TestCoinSelectors(t *testing.T) {
+	tests := []struct {
+		name        string
+		selector    CoinSelector
+		amount      uint64
+		maxInputs   int
+		expected    []byte
+		expectedErr error
+	}{
+		{
+			name:     "largest first",
+			selector: LargestFirst{},
+			amount:   25,
+			expected: []byte{5, 4},
+		},
+		{
+			name:        "largest first input limit",
+			selector:    LargestFirst{},
+			amount:      25,
+			maxInputs:   1,
+			expectedErr: errTooManyInputs,
+		},
+		{
+			name:        "largest first insufficient funds",
+			selector:    LargestFirst{},
+			amount:      100,
+			expectedErr: errInsufficientFunds,
+		},
+		{
+			name:     "smallest first",
+			selector: SmallestFirst{},
+			amount:   7,
+			expected: []byte{1, 2, 3},
+		},
+		{
+			name:      "smallest first topped up with the largest",
+			selector:  SmallestFirst{},
+			amount:    16,
+			maxInputs: 3,
+			expected:  []byte{1, 2, 5},
+		},
+		{
+			name:      "smallest first only the largest fit",
+			selector:  SmallestFirst{},
+			amount:    30,
+			maxInputs: 2,
+			expected:  []byte{5, 4},
+		},
+		{
+			name:        "smallest first input limit",
+			selector:    SmallestFirst{},
+			amount:      31,
+			maxInputs:   2,
+			expectedErr: errTooManyInputs,
+		},
+		{
+			name:        "smallest first insufficient funds",
+			selector:    SmallestFirst{},
+			amount:      100,
+			expectedErr: errInsufficientFunds,
+		},
+		{
+			name:     "fewest inputs single coin",
+			selector: FewestInputs{},
+			amount:   12,
+			expected: []byte{5},
+		},
+		{
+			name:     "fewest inputs prefers smaller coins",
+			selector: FewestInputs{},
+			amount:   25,
+			expected: []byte{3, 5},
+		},
+		{
+			name:        "fewest inputs input limit",
+			selector:    FewestInputs{},
+			amount:      25,
+			maxInputs:   1,
+			expectedErr: errTooManyInputs,
+		},
+		{
+			name:        "fewest inputs insufficient funds",
+			selector:    FewestInputs{},
+			amount:      100,
+			expectedErr: errInsufficientFunds,
+		},
+		{
+			name:     "branch and bound exact match",
+			selector: &BranchAndBound{},
+			amount:   17,
+			expected: []byte{4, 3, 2},
+		},
+		{
+			name:        "branch and bound no exact match",
+			selector:    &BranchAndBound{},
+			amount:      37,
+			maxInputs:   3,
+			expectedErr: errNoExactMatch,
+		},
+		{
+			name:      "branch and bound input limit falls back",
+			selector:  &BranchAndBound{Fallback: LargestFirst{}},
+			amount:    17,
+			maxInputs: 2,
+			expected:  []byte{5},
+		},
+		{
+			name:        "branch and bound insufficient funds",
+			selector:    &BranchAndBound{Fallback: LargestFirst{}},
+			amount:      100,
+			expectedErr: errInsufficientFunds,
+		},
+	}
+	// The selection must not depend on the order of the coins
+	orders := [][]byte{
+		{1, 2, 3, 4, 5},
+		{5, 4, 3, 2, 1},
+		{3, 5, 1, 4, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, order := range orders {
+				selected, err := test.selector.Select(newTestCoins(order), test.amount, test.maxInputs)
+				if !errors.Is(err, test.expectedErr) {
+					t.Fatalf("with coins in order %v, expected error %v, got %v", order, test.expectedErr, err)
+				}
+				if err != nil {
+					continue
+				}
+
+				if test.maxInputs > 0 && len(selected) > test.maxInputs {
+					t.Fatalf("with coins in order %v, selected %d coins but at most %d are allowed", order, len(selected), test.maxInputs)
+				}
+				if sum := sumCoins(selected); sum < test.amount {
+					t.Fatalf("with coins in order %v, selected %d but needed %d", order, sum, test.amount)
+				}
+				txIDs := make([]byte, len(selected))
+				for i, coin := range selected {
+					txIDs[i] = coin.UTXO.TxID[0]
+				}
+				if string(txIDs) != string(test.expected) {
+					t.Fatalf("with coins in order %v, expected coins %v, got %v", order, test.expected, txIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestSortCoinsBreaksTiesByUTXOID(t *testing.T) {
+	for _, descending := range []bool{false, true} {
+		coins := []*Coin{newTestCoin(2, 5), newTestCoin(1, 5)}
+		sortCoins(coins, descending)
+		if coins[0].UTXO.TxID[0] != 1 {
+			t.Fatalf("expected the coin with the lower UTXO ID first when descending is %t", descending)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/utils/math"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+)
+
+var (
+	errTooFewInputs    = errors.New("a consolidation transaction needs at least 2 inputs")
+	errTooFewMaxInputs = errors.New("a consolidation transaction that pays a fee needs to allow at least 3 inputs")
+)
+
+// ConsolidateConfig configures how UTXOs are consolidated
+type ConsolidateConfig struct {
+	// Target is the number of UTXOs that each asset and owner is merged into.
+	// Defaults to 1.
+	Target int
+	// MaxInputs is the most inputs a transaction may have. Defaults to 256.
+	// If a fee is paid, it must be at least 3 so that the transactions merging
+	// assets other than the fee asset have room for a fee input.
+	MaxInputs int
+	// Change chooses the owners of the fee asset's change when merging other
+	// assets. Defaults to the signer's first address.
+	Change ChangePolicy
+}
+
+// ConsolidateXChain merges the X-chain UTXOs of the signer, so that each
+// asset and owner has at most [config.Target] UTXOs. UTXOs are only merged
+// with others of the same asset that have the same owners, so each merged
+// UTXO keeps the owners, threshold, and locktime of the UTXOs it replaces.
+//
+// As many transactions as needed are issued, one at a time. A transaction
+// merging assets other than [feeAssetID] pays its fee from the signer's
+// [feeAssetID] UTXOs.
+func ConsolidateXChain(
+	networkID uint32,
+	chainID ids.ID,
+	xClient *avm.Client,
+	s signer.Signer,
+	feeAssetID ids.ID,
+	feeAmount uint64,
+	config ConsolidateConfig,
+) error {
+	source := NewUTXOSet(
+		NewXChainUTXOSource(networkID, xClient, s.Addresses()),
+		signer.AddressSet(s),
+	)
+	kind := &BaseTxKind{
+		NetworkID: networkID,
+		ChainID:   chainID,
+	}
+	c := &consolidator{
+		source:     source,
+		kind:       kind,
+		signer:     s,
+		addrs:      signer.AddressSet(s),
+		feeAssetID: feeAssetID,
+		fee:        feeAmount,
+		target:     config.Target,
+		maxInputs:  config.MaxInputs,
+		change:     config.Change,
+		skipped:    make(map[string]bool),
+	}
+	if c.target <= 0 {
+		c.target = 1
+	}
+	if c.maxInputs <= 0 {
+		c.maxInputs = DefaultMaxInputs
+	}
+	if c.fee > 0 && c.maxInputs < 3 {
+		return errTooFewMaxInputs
+	}
+
+	p := &Pipeline{
+		Issuer:    xClient,
+		Confirmer: NewXChainConfirmer(xClient),
+		Retries:   defaultRetries,
+	}
+	return c.run(p)
+}
+
+// coinGroup is the spendable UTXOs of one asset with the same owners
+type coinGroup struct {
+	key     string
+	assetID ids.ID
+	owners  secp256k1fx.OutputOwners
+	coins   []*Coin
+}
+
+type consolidator struct {
+	source     *UTXOSet
+	kind       TxKind
+	signer     signer.Signer
+	addrs      ids.ShortSet
+	feeAssetID ids.ID
+	fee        uint64
+	target     int
+	maxInputs  int
+	change     ChangePolicy
+
+	// skipped are the groups that can't be merged any further
+	skipped map[string]bool
+}
+
+// run issues consolidation transactions with [p] until every group has been
+// merged into at most [c.target] UTXOs
+func (c *consolidator) run(p *Pipeline) error {
+	var (
+		start     = time.Now()
+		numTxs    int
+		numMerged int
+	)
+	for {
+		groups, err := c.groups()
+		if err != nil {
+			return err
+		}
+		if len(groups) == 0 {
+			break
+		}
+
+		group := groups[0]
+		tx, numInputs, err := c.build(group)
+		if err != nil {
+			return fmt.Errorf("couldn't consolidate asset %s: %w", group.assetID, err)
+		}
+		if tx == nil {
+			c.skipped[group.key] = true
+			continue
+		}
+
+		if err := c.issue(p, numTxs, tx); err != nil {
+			return err
+		}
+		numTxs++
+		numMerged += numInputs
+
+		remaining := 0
+		for _, g := range groups {
+			remaining += len(g.coins) - c.target
+		}
+		remaining -= numInputs - 1
+		log.Printf("%s - %s - merged %d UTXOs of asset %s - %d transactions - %d UTXOs left to merge",
+			tx.ID,
+			choices.Accepted,
+			numInputs,
+			group.assetID,
+			numTxs,
+			remaining,
+		)
+	}
+	log.Printf("merged %d UTXOs in %d transactions in %s", numMerged, numTxs, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// groups returns the groups with more than [c.target] UTXOs that haven't been
+// skipped, ordered by their asset and owners
+func (c *consolidator) groups() ([]*coinGroup, error) {
+	utxos, err := c.source.UTXOs()
+	if err != nil {
+		return nil, err
+	}
+
+	utxoIDs := make([]ids.ID, 0, len(utxos))
+	for utxoID := range utxos {
+		utxoIDs = append(utxoIDs, utxoID)
+	}
+	ids.SortIDs(utxoIDs)
+
+	var (
+		now    = uint64(time.Now().Unix())
+		groups = make(map[string]*coinGroup)
+	)
+	for _, utxoID := range utxoIDs {
+		utxo := utxos[utxoID]
+		out, ok := utxo.Out.(*secp256k1fx.TransferOutput)
+		if !ok {
+			continue
+		}
+		input, inputSigners, err := spend(out, c.addrs, now)
+		if err != nil {
+			// this utxo can't be spent with the current keys right now
+			continue
+		}
+
+		transferable := &avax.TransferableOutput{Asset: utxo.Asset, Out: out}
+		key, err := outputKey(transferable)
+		if err != nil {
+			return nil, err
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &coinGroup{
+				key:     key,
+				assetID: utxo.AssetID(),
+				owners:  out.OutputOwners,
+			}
+			groups[key] = group
+		}
+		group.coins = append(group.coins, &Coin{
+			UTXO:    utxo,
+			Input:   input,
+			Signers: inputSigners,
+		})
+	}
+
+	var unmerged []*coinGroup
+	for key, group := range groups {
+		if len(group.coins) > c.target && !c.skipped[key] {
+			unmerged = append(unmerged, group)
+		}
+	}
+	// Merging other assets may pay its fee with, and return change to, the
+	// fee asset. So the fee asset is merged last.
+	sort.Slice(unmerged, func(i, j int) bool {
+		isFeeI, isFeeJ := unmerged[i].assetID == c.feeAssetID, unmerged[j].assetID == c.feeAssetID
+		if isFeeI != isFeeJ {
+			return isFeeJ
+		}
+		return unmerged[i].key < unmerged[j].key
+	})
+	return unmerged, nil
+}
+
+// build returns a signed transaction merging the smallest UTXOs of [group],
+// and the number of them that it merges. If the group can't be merged, nil is
+// returned.
+func (c *consolidator) build(group *coinGroup) (*Tx, int, error) {
+	sortCoins(group.coins, false)
+
+	// Merging [numInputs] UTXOs into 1 leaves the group with [target] UTXOs
+	numInputs := len(group.coins) - c.target + 1
+	maxInputs := c.maxInputs
+	if group.assetID != c.feeAssetID && c.fee > 0 {
+		// Leave an input for the fee
+		maxInputs--
+	}
+	if numInputs > maxInputs {
+		numInputs = maxInputs
+	}
+	for {
+		if numInputs < 2 {
+			return nil, 0, errTooFewInputs
+		}
+
+		spend, err := c.buildWith(group, group.coins[:numInputs])
+		if err != nil {
+			return nil, 0, err
+		}
+		if spend == nil {
+			log.Printf("skipping asset %s as its %d smallest UTXOs don't cover the fee", group.assetID, numInputs)
+			return nil, 0, nil
+		}
+		if SignedTxSize(spend.tx, spend.signers) > maxTxSize {
+			discardChange(c.change, spend.change)
+			numInputs /= 2
+			continue
+		}
+
+		txBytes, err := signer.SignTx(spend.tx, c.signer, spend.signers)
+		if err != nil {
+			discardChange(c.change, spend.change)
+			return nil, 0, err
+		}
+		txID := hashing.ComputeHash256Array(txBytes)
+		return newTx(txID, txBytes, spend.ins, spend.produced), numInputs, nil
+	}
+}
+
+// buildWith returns an unsigned transaction merging [coins], which are from
+// [group], into a single UTXO with the group's owners. If [coins] are of the
+// fee asset and don't cover the fee, nil is returned.
+func (c *consolidator) buildWith(group *coinGroup, coins []*Coin) (*unsignedSpend, error) {
+	var merged uint64
+	ins := make([]*avax.TransferableInput, len(coins))
+	signers := make([][]ids.ShortID, len(coins))
+	for i, coin := range coins {
+		var err error
+		merged, err = math.Add64(merged, coin.Amount())
+		if err != nil {
+			return nil, errSpendOverflow
+		}
+		ins[i] = &avax.TransferableInput{
+			UTXOID: coin.UTXO.UTXOID,
+			Asset:  avax.Asset{ID: group.assetID},
+			In:     coin.Input,
+		}
+		signers[i] = coin.Signers
+	}
+
+	var (
+		change      []*avax.TransferableOutput
+		changeOwner *secp256k1fx.OutputOwners
+	)
+	if group.assetID == c.feeAssetID {
+		if merged <= c.fee {
+			return nil, nil
+		}
+		merged -= c.fee
+	} else if c.fee > 0 {
+		feeUTXOs, err := c.feeUTXOs()
+		if err != nil {
+			return nil, err
+		}
+		cost := map[ids.ID]uint64{c.feeAssetID: c.fee}
+		spent, feeIns, feeSigners, err := BuildInputs(feeUTXOs, c.addrs, cost, LargestFirst{}, c.maxInputs-len(coins))
+		if err != nil {
+			return nil, err
+		}
+		ins = append(ins, feeIns...)
+		signers = append(signers, feeSigners...)
+
+		consumed := make([]*avax.UTXO, 0, len(ins))
+		for _, coin := range coins {
+			consumed = append(consumed, coin.UTXO)
+		}
+		for _, in := range feeIns {
+			consumed = append(consumed, feeUTXOs[in.InputID()])
+		}
+		changeOwner, err = changeOwners(c.change, c.signer, consumed)
+		if err != nil {
+			return nil, err
+		}
+		change = GetChangeOutputs(changeOwner, cost, spent)
+		if len(change) == 0 {
+			// The owners aren't paid, so they may be chosen again
+			discardChange(c.change, changeOwner)
+			changeOwner = nil
+		}
+	}
+	sortInputsWithSigners(ins, signers)
+
+	owners := group.owners
+	outs := []*avax.TransferableOutput{{
+		Asset: avax.Asset{ID: group.assetID},
+		Out: &secp256k1fx.TransferOutput{
+			Amt: merged,
+			OutputOwners: secp256k1fx.OutputOwners{
+				Locktime:  owners.Locktime,
+				Threshold: owners.Threshold,
+				Addrs:     owners.Addrs,
+			},
+		},
+	}}
+	tx, produced, err := c.kind.Build(outs, change, ins)
+	if err != nil {
+		discardChange(c.change, changeOwner)
+		return nil, err
+	}
+	return &unsignedSpend{
+		tx:       tx,
+		ins:      ins,
+		signers:  signers,
+		produced: produced,
+		change:   changeOwner,
+	}, nil
+}
+
+// feeUTXOs returns the UTXOs of the fee asset. Merging the fee asset is done
+// with its own transactions, so the fee may be paid by any of them.
+func (c *consolidator) feeUTXOs() (map[ids.ID]*avax.UTXO, error) {
+	utxos, err := c.source.UTXOs()
+	if err != nil {
+		return nil, err
+	}
+	feeUTXOs := make(map[ids.ID]*avax.UTXO)
+	for utxoID, utxo := range utxos {
+		if utxo.AssetID() == c.feeAssetID {
+			feeUTXOs[utxoID] = utxo
+		}
+	}
+	return feeUTXOs, nil
+}
+
+// issue issues [tx], the [i]th consolidation transaction, with [p] and waits
+// for it to be accepted
+func (c *consolidator) issue(p *Pipeline, i int, tx *Tx) error {
+	if err := p.issueTx(tx.ID, tx.Bytes); err != nil {
+		return fmt.Errorf("couldn't issue transaction %d (%s): %w", i, tx.ID, err)
+	}
+	c.source.Issued(tx)
+	changeIssued(c.change, tx)
+
+	status, err := p.confirm(tx.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't confirm transaction %d (%s): %w", i, tx.ID, err)
+	}
+	if status != choices.Accepted {
+		return fmt.Errorf("%w: transaction %d (%s) has status %s", errNotAccepted, i, tx.ID, status)
+	}
+	return nil
+}
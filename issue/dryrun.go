@@ -0,0 +1,172 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/utils/math"
+
+	"github.com/StephenButtolph/avalanche-tooling/inspect"
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+var (
+	_ Issuer    = &DryRun{}
+	_ Confirmer = &DryRun{}
+)
+
+// DryRun records the transactions of a send instead of issuing them. It
+// stands in for both the issuer and the confirmer, and reports every
+// transaction as accepted so that later transactions spend its change.
+type DryRun struct {
+	lock    sync.Mutex
+	signers ids.ShortSet
+
+	txs      []DryRunTx
+	consumed []inspect.Input
+	sent     map[ids.ID]uint64
+	fees     map[ids.ID]uint64
+	// lastTx is used to format the totals of the network's AVAX asset
+	lastTx *inspect.Summary
+}
+
+// DryRunTx is a signed transaction that wasn't issued
+type DryRunTx struct {
+	ID ids.ID `json:"id"`
+	// Hex is the signed transaction in the hex encoding of the avalanchego
+	// APIs
+	Hex string           `json:"hex"`
+	Tx  *inspect.Summary `json:"tx"`
+}
+
+// DryRunReport describes the transactions recorded by a DryRun
+type DryRunReport struct {
+	NumTxs int `json:"numTxs"`
+	// Sent are the totals of each asset that would be exported or sent to
+	// addresses other than the signer's
+	Sent []inspect.Amount `json:"sent"`
+	Fees []inspect.Amount `json:"fees"`
+	// Consumed are the UTXOs that would be spent
+	Consumed []inspect.Input `json:"consumed"`
+	Txs      []DryRunTx      `json:"txs"`
+}
+
+// NewDryRun returns a DryRun that treats outputs owned only by [signers] as
+// change.
+func NewDryRun(signers ids.ShortSet) *DryRun {
+	return &DryRun{
+		signers: signers,
+		sent:    make(map[ids.ID]uint64),
+		fees:    make(map[ids.ID]uint64),
+	}
+}
+
+// IssueTx records the signed transaction [txBytes] and returns its ID
+func (d *DryRun) IssueTx(txBytes []byte) (ids.ID, error) {
+	unsignedTx, err := txs.ParseSigned(txs.Unknown, txBytes)
+	if err != nil {
+		return ids.Empty, err
+	}
+	summary, err := inspect.Describe(unsignedTx)
+	if err != nil {
+		return ids.Empty, err
+	}
+	txHex, err := txio.CheckedHex.Encode(txBytes)
+	if err != nil {
+		return ids.Empty, err
+	}
+	txID := hashing.ComputeHash256Array(txBytes)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, out := range summary.Outputs {
+		if out.Kind == inspect.KindOutput && d.isChange(&out.Owners) {
+			continue
+		}
+		if err := addAmount(d.sent, out.AssetID, out.Amount.Amount); err != nil {
+			return ids.Empty, err
+		}
+	}
+	for _, fee := range summary.Fees {
+		if err := addAmount(d.fees, fee.AssetID, fee.Amount); err != nil {
+			return ids.Empty, err
+		}
+	}
+	d.consumed = append(d.consumed, summary.Inputs...)
+	d.txs = append(d.txs, DryRunTx{
+		ID:  txID,
+		Hex: txHex,
+		Tx:  summary,
+	})
+	d.lastTx = summary
+	return txID, nil
+}
+
+// Confirm reports every recorded transaction as accepted
+func (*DryRun) Confirm(ids.ID) (choices.Status, error) {
+	return choices.Accepted, nil
+}
+
+// Report describes the transactions that have been recorded
+func (d *DryRun) Report() *DryRunReport {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return &DryRunReport{
+		NumTxs:   len(d.txs),
+		Sent:     d.totals(d.sent),
+		Fees:     d.totals(d.fees),
+		Consumed: d.consumed,
+		Txs:      d.txs,
+	}
+}
+
+// isChange returns true if [owners] are only addresses of the signer
+func (d *DryRun) isChange(owners *inspect.Owners) bool {
+	if len(owners.Addresses) == 0 {
+		return false
+	}
+	for _, addrStr := range owners.Addresses {
+		_, _, addrBytes, err := formatting.ParseAddress(addrStr)
+		if err != nil {
+			return false
+		}
+		addr, err := ids.ToShortID(addrBytes)
+		if err != nil || !d.signers.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *DryRun) totals(amounts map[ids.ID]uint64) []inspect.Amount {
+	assetIDs := make([]ids.ID, 0, len(amounts))
+	for assetID := range amounts {
+		assetIDs = append(assetIDs, assetID)
+	}
+	ids.SortIDs(assetIDs)
+
+	totals := make([]inspect.Amount, len(assetIDs))
+	for i, assetID := range assetIDs {
+		totals[i] = d.lastTx.Amount(assetID, amounts[assetID])
+	}
+	return totals
+}
+
+func addAmount(amounts map[ids.ID]uint64, assetID ids.ID, amount uint64) error {
+	total, err := math.Add64(amounts[assetID], amount)
+	if err != nil {
+		return fmt.Errorf("total of asset %s overflows", assetID)
+	}
+	amounts[assetID] = total
+	return nil
+}
@@ -6,18 +6,16 @@ package issue
 import (
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
-	"github.com/ava-labs/avalanchego/utils/constants"
-	"github.com/ava-labs/avalanchego/utils/crypto"
-	"github.com/ava-labs/avalanchego/utils/formatting"
 	"github.com/ava-labs/avalanchego/utils/math"
 	"github.com/ava-labs/avalanchego/vms/avm"
 	"github.com/ava-labs/avalanchego/vms/components/avax"
 	"github.com/ava-labs/avalanchego/vms/platformvm"
 	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/signer"
 )
 
 const (
@@ -27,81 +25,116 @@ const (
 var (
 	errDuplicatedAddresses = errors.New("duplicated addresses")
 	errSpendOverflow       = errors.New("spent amount overflows uint64")
+	errInsufficientFunds   = errors.New("insufficient funds")
 )
 
+// SpendConfig configures how the transactions of a send are funded
+type SpendConfig struct {
+	// Selector chooses the UTXOs spent by each transaction. Defaults to
+	// LargestFirst.
+	Selector CoinSelector
+	// MaxInputs, if positive, is the most inputs a transaction may have
+	MaxInputs int
+	// Change chooses the owners of each transaction's change. Defaults to the
+	// signer's first address.
+	Change ChangePolicy
+}
+
+// MaxChangeAddrs returns the most addresses that change may be owned by
+func (c SpendConfig) MaxChangeAddrs() int {
+	if c.Change == nil {
+		return 1
+	}
+	return c.Change.MaxAddrs()
+}
+
+// SendConfig configures how the transactions of a send are issued
+type SendConfig struct {
+	SpendConfig
+	// MaxInFlight is the number of builders that issue transactions at once,
+	// each spending from its own share of the UTXOs. Each builder may have
+	// several transactions that spend each other's change in flight.
+	MaxInFlight int
+	// Journal, if set, records which transactions were sent so that an
+	// interrupted send can be resumed
+	Journal *Journal
+	// DryRun, if set, records the transactions instead of issuing them
+	DryRun *DryRun
+}
+
+// SendOutputsOtherToP imports [txOuts] into the P-chain, one transaction per
+// batch, funded by the signer's UTXOs exported from [sourceChainID]. The
+// transactions are issued as configured by [config].
 func SendOutputsOtherToP(
 	networkID uint32,
 	chainID ids.ID,
 	sourceChainID ids.ID,
 	pClient *platformvm.Client,
-	keychain *secp256k1fx.Keychain,
+	s signer.Signer,
 	txOuts [][]*avax.TransferableOutput,
 	feeAssetID ids.ID,
 	feeAmount uint64,
+	config SendConfig,
 ) error {
-	numSent := 0
-	for _, outs := range txOuts {
-		cost, err := GetCost(outs, feeAssetID, feeAmount)
-		if err != nil {
-			return err
-		}
-
-		utxos, err := GetPChainAtomicUTXOs(networkID, sourceChainID, pClient, keychain)
-		if err != nil {
-			return err
-		}
-
-		spent, ins, keys, err := BuildInputs(utxos, keychain, cost)
-		if err != nil {
-			return err
-		}
-
-		changeAddr := keychain.Keys[0].PublicKey().Address()
-
-		changeOutputs := GetChangeOutputs(changeAddr, cost, spent)
-
-		newOuts := []*avax.TransferableOutput(nil)
-		newOuts = append(newOuts, outs...)
-		newOuts = append(newOuts, changeOutputs...)
-		avax.SortTransferableOutputs(outs, platformvm.Codec)
-
-		tx, err := BuildImportTx(networkID, chainID, sourceChainID, newOuts, ins, keys)
-		if err != nil {
-			return err
-		}
-
-		{
-			// txID := tx.ID()
-			// txJSON, err := json.MarshalIndent(tx, "", "  ")
-			// if err != nil {
-			// 	return err
-			// }
-			// log.Printf("txID = %s\n%s", txID, string(txJSON))
-		}
-
-		txBytes := tx.Bytes()
-
-		txID, err := pClient.IssueTx(txBytes)
-		if err != nil {
-			return err
-		}
-
-		txStatus, err := ConfirmTx(pClient, txID, 100, 100*time.Millisecond)
-		if err != nil {
-			return err
-		}
+	source := NewUTXOSet(
+		NewPChainAtomicUTXOSource(networkID, sourceChainID, pClient, s.Addresses()),
+		signer.AddressSet(s),
+	)
+	kind := &ImportTxKind{
+		NetworkID:     networkID,
+		ChainID:       chainID,
+		SourceChainID: sourceChainID,
+	}
+	return send(source, kind, s, pClient, NewPChainConfirmer(pClient), txOuts, feeAssetID, feeAmount, config)
+}
 
-		addrStr, err := formatting.FormatAddress("P", constants.GetHRP(networkID), changeAddr[:])
-		if err != nil {
-			return err
+// send issues [txOuts] with [config.MaxInFlight] builders at once, each
+// spending from its own share of the signer's UTXOs.
+func send(
+	source *UTXOSet,
+	kind TxKind,
+	s signer.Signer,
+	issuer Issuer,
+	confirmer Confirmer,
+	txOuts [][]*avax.TransferableOutput,
+	feeAssetID ids.ID,
+	feeAmount uint64,
+	config SendConfig,
+) error {
+	chains, err := source.Split(config.MaxInFlight)
+	if err != nil {
+		return err
+	}
+	change := config.Change
+	if config.DryRun != nil {
+		// The change of a dry run isn't paid
+		change = untracked(change)
+	}
+	builders := make([]Builder, len(chains))
+	for i, chain := range chains {
+		builders[i] = &SpendBuilder{
+			Source:     chain,
+			Kind:       kind,
+			Signer:     s,
+			FeeAssetID: feeAssetID,
+			Fee:        feeAmount,
+			Selector:   config.Selector,
+			MaxInputs:  config.MaxInputs,
+			Change:     change,
 		}
-
-		numSent += len(outs)
-		log.Printf("%s - %s - %s - %d", txID, txStatus, addrStr, numSent)
-
-		time.Sleep(1 * time.Second)
 	}
-	return nil
+	if config.DryRun != nil {
+		issuer = config.DryRun
+		confirmer = config.DryRun
+	}
+	p := &Pipeline{
+		Builders:  builders,
+		Issuer:    issuer,
+		Confirmer: confirmer,
+		Retries:   defaultRetries,
+		Journal:   config.Journal,
+	}
+	return p.Send(txOuts)
 }
 
 func BuildImportTx(
@@ -110,7 +143,8 @@ func BuildImportTx(
 	sourceChainID ids.ID,
 	outs []*avax.TransferableOutput,
 	ins []*avax.TransferableInput,
-	keys [][]*crypto.PrivateKeySECP256K1R,
+	s signer.Signer,
+	signers [][]ids.ShortID,
 ) (
 	*platformvm.Tx,
 	error,
@@ -124,77 +158,33 @@ func BuildImportTx(
 		SourceChain:    sourceChainID,
 		ImportedInputs: ins,
 	}}
-	return tx, tx.Sign(platformvm.Codec, keys)
+	return tx, signPlatformTx(tx, s, signers)
 }
 
+// SendOutputsXToOther exports [txOuts] from the X-chain to
+// [destinationChainID], one transaction per batch, funded by the signer's
+// X-chain UTXOs. The transactions are issued as configured by [config].
 func SendOutputsXToOther(
 	networkID uint32,
 	chainID ids.ID,
 	destinationChainID ids.ID,
 	xClient *avm.Client,
-	keychain *secp256k1fx.Keychain,
+	s signer.Signer,
 	txOuts [][]*avax.TransferableOutput,
 	feeAssetID ids.ID,
 	feeAmount uint64,
+	config SendConfig,
 ) error {
-	for _, outs := range txOuts {
-		cost, err := GetCost(outs, feeAssetID, feeAmount)
-		if err != nil {
-			return err
-		}
-
-		utxos, err := GetXChainUTXOs(networkID, xClient, keychain)
-		if err != nil {
-			return err
-		}
-
-		spent, ins, keys, err := BuildInputs(utxos, keychain, cost)
-		if err != nil {
-			return err
-		}
-
-		changeAddr := keychain.Keys[0].PublicKey().Address()
-
-		changeOutputs := GetChangeOutputs(changeAddr, cost, spent)
-
-		avax.SortTransferableOutputs(outs, c)
-		avax.SortTransferableOutputs(changeOutputs, c)
-
-		tx, err := BuildExportTx(networkID, chainID, destinationChainID, outs, changeOutputs, ins, keys)
-		if err != nil {
-			return err
-		}
-		txBytes := tx.Bytes()
-
-		{
-			// txID := tx.ID()
-			// txJSON, err := json.MarshalIndent(tx, "", "  ")
-			// if err != nil {
-			// 	return err
-			// }
-			// log.Printf("txID = %s\n%s", txID, string(txJSON))
-		}
-
-		txID, err := xClient.IssueTx(txBytes)
-		if err != nil {
-			return err
-		}
-
-		txStatus, err := xClient.ConfirmTx(txID, 100, 100*time.Millisecond)
-		if err != nil {
-			return err
-		}
-
-		addrStr, err := formatting.FormatAddress("X", constants.GetHRP(networkID), changeAddr[:])
-		if err != nil {
-			return err
-		}
-
-		log.Printf("%s - %s - %s", txID, txStatus, addrStr)
-
-		time.Sleep(1 * time.Second)
+	source := NewUTXOSet(
+		NewXChainUTXOSource(networkID, xClient, s.Addresses()),
+		signer.AddressSet(s),
+	)
+	kind := &ExportTxKind{
+		NetworkID:          networkID,
+		ChainID:            chainID,
+		DestinationChainID: destinationChainID,
 	}
-	return nil
+	return send(source, kind, s, xClient, NewXChainConfirmer(xClient), txOuts, feeAssetID, feeAmount, config)
 }
 
 func BuildExportTx(
@@ -204,7 +194,8 @@ func BuildExportTx(
 	exportedOuts []*avax.TransferableOutput,
 	returnedOuts []*avax.TransferableOutput,
 	ins []*avax.TransferableInput,
-	keys [][]*crypto.PrivateKeySECP256K1R,
+	s signer.Signer,
+	signers [][]ids.ShortID,
 ) (
 	*avm.Tx,
 	error,
@@ -219,69 +210,31 @@ func BuildExportTx(
 		DestinationChain: destinationChainID,
 		ExportedOuts:     exportedOuts,
 	}}
-	return tx, tx.SignSECP256K1Fx(c, keys)
+	return tx, signAVMTx(tx, s, signers)
 }
 
+// SendOutputsXToX sends [txOuts] on the X-chain, one transaction per batch,
+// funded by the signer's X-chain UTXOs. The transactions are issued as
+// configured by [config].
 func SendOutputsXToX(
 	networkID uint32,
 	chainID ids.ID,
 	xClient *avm.Client,
-	keychain *secp256k1fx.Keychain,
+	s signer.Signer,
 	txOuts [][]*avax.TransferableOutput,
 	feeAssetID ids.ID,
 	feeAmount uint64,
+	config SendConfig,
 ) error {
-	for _, outs := range txOuts {
-		cost, err := GetCost(outs, feeAssetID, feeAmount)
-		if err != nil {
-			return err
-		}
-
-		utxos, err := GetXChainUTXOs(networkID, xClient, keychain)
-		if err != nil {
-			return err
-		}
-
-		spent, ins, keys, err := BuildInputs(utxos, keychain, cost)
-		if err != nil {
-			return err
-		}
-
-		changeAddr := keychain.Keys[0].PublicKey().Address()
-
-		changeOutputs := GetChangeOutputs(changeAddr, cost, spent)
-
-		newOuts := []*avax.TransferableOutput(nil)
-		newOuts = append(newOuts, outs...)
-		newOuts = append(newOuts, changeOutputs...)
-		avax.SortTransferableOutputs(outs, c)
-
-		tx, err := BuildBaseTx(networkID, chainID, newOuts, ins, keys)
-		if err != nil {
-			return err
-		}
-		txBytes := tx.Bytes()
-
-		txID, err := xClient.IssueTx(txBytes)
-		if err != nil {
-			return err
-		}
-
-		txStatus, err := xClient.ConfirmTx(txID, 100, 100*time.Millisecond)
-		if err != nil {
-			return err
-		}
-
-		addrStr, err := formatting.FormatAddress("X", "fuji", changeAddr[:])
-		if err != nil {
-			return err
-		}
-
-		log.Printf("%s - %s - %s", txID, txStatus, addrStr)
-
-		time.Sleep(1 * time.Second)
+	source := NewUTXOSet(
+		NewXChainUTXOSource(networkID, xClient, s.Addresses()),
+		signer.AddressSet(s),
+	)
+	kind := &BaseTxKind{
+		NetworkID: networkID,
+		ChainID:   chainID,
 	}
-	return nil
+	return send(source, kind, s, xClient, NewXChainConfirmer(xClient), txOuts, feeAssetID, feeAmount, config)
 }
 
 func BuildBaseTx(
@@ -289,7 +242,8 @@ func BuildBaseTx(
 	chainID ids.ID,
 	outs []*avax.TransferableOutput,
 	ins []*avax.TransferableInput,
-	keys [][]*crypto.PrivateKeySECP256K1R,
+	s signer.Signer,
+	signers [][]ids.ShortID,
 ) (
 	*avm.Tx,
 	error,
@@ -300,11 +254,13 @@ func BuildBaseTx(
 		Outs:         outs,
 		Ins:          ins,
 	}}}
-	return tx, tx.SignSECP256K1Fx(c, keys)
+	return tx, signAVMTx(tx, s, signers)
 }
 
+// GetChangeOutputs returns the outputs, owned by [owners], of the amounts
+// [consumed] in excess of those [produced]
 func GetChangeOutputs(
-	changeAddress ids.ShortID,
+	owners *secp256k1fx.OutputOwners,
 	produced,
 	consumed map[ids.ID]uint64,
 ) []*avax.TransferableOutput {
@@ -317,12 +273,8 @@ func GetChangeOutputs(
 			outs = append(outs, &avax.TransferableOutput{
 				Asset: avax.Asset{ID: assetID},
 				Out: &secp256k1fx.TransferOutput{
-					Amt: amountConsumed - amountProduced,
-					OutputOwners: secp256k1fx.OutputOwners{
-						Locktime:  0,
-						Threshold: 1,
-						Addrs:     []ids.ShortID{changeAddress},
-					},
+					Amt:          amountConsumed - amountProduced,
+					OutputOwners: *owners,
 				},
 			})
 		}
@@ -354,103 +306,144 @@ func GetCost(
 	return amounts, nil
 }
 
+// BuildInputs returns inputs, spending [utxos] authorized by [addrs], worth
+// at least [amounts]. The inputs of each asset are chosen by [selector], which
+// defaults to LargestFirst. If [maxInputs] is positive, at most [maxInputs]
+// inputs are returned. Locked UTXOs are skipped, and their amounts are reported
+// if the rest don't suffice.
 func BuildInputs(
 	utxos map[ids.ID]*avax.UTXO,
-	keychain *secp256k1fx.Keychain,
+	addrs ids.ShortSet,
 	amounts map[ids.ID]uint64,
+	selector CoinSelector,
+	maxInputs int,
 ) (
 	map[ids.ID]uint64,
 	[]*avax.TransferableInput,
-	[][]*crypto.PrivateKeySECP256K1R,
+	[][]ids.ShortID,
 	error,
 ) {
-	amountsSpent := make(map[ids.ID]uint64, len(amounts))
-	time := uint64(time.Now().Unix())
+	if selector == nil {
+		selector = LargestFirst{}
+	}
 
-	ins := []*avax.TransferableInput{}
-	keys := [][]*crypto.PrivateKeySECP256K1R{}
-	for _, utxo := range utxos {
-		assetID := utxo.AssetID()
-		amount := amounts[assetID]
-		amountSpent := amountsSpent[assetID]
+	utxoIDs := make([]ids.ID, 0, len(utxos))
+	for utxoID := range utxos {
+		utxoIDs = append(utxoIDs, utxoID)
+	}
+	ids.SortIDs(utxoIDs)
 
-		if amountSpent >= amount {
-			// we already have enough inputs allocated to this asset
+	time := uint64(time.Now().Unix())
+	coins := make(map[ids.ID][]*Coin, len(amounts))
+	// locked are the amounts of each asset held by UTXOs that can't be spent
+	// until they unlock, so that they can be reported if funds run out
+	locked := make(map[ids.ID]uint64)
+	for _, utxoID := range utxoIDs {
+		utxo := utxos[utxoID]
+		assetID := utxo.AssetID()
+		if amounts[assetID] == 0 {
+			// this asset doesn't need to be spent
 			continue
 		}
 
-		inputIntf, signers, err := keychain.Spend(utxo.Out, time)
-		if err != nil {
-			// this utxo can't be spent with the current keys right now
-			continue
-		}
-		input, ok := inputIntf.(avax.TransferableIn)
-		if !ok {
-			// this input doesn't have an amount, so I don't care about it here
+		input, inputSigners, err := spend(utxo.Out, addrs, time)
+		if errors.Is(err, errLocked) {
+			if out, ok := utxo.Out.(avax.TransferableOut); ok {
+				locked[assetID] = addSaturating(locked[assetID], out.Amount())
+			}
 			continue
 		}
-		newAmountSpent, err := math.Add64(amountSpent, input.Amount())
 		if err != nil {
-			// there was an error calculating the consumed amount, just error
-			return nil, nil, nil, errSpendOverflow
+			// this utxo can't be spent with the current keys
+			continue
 		}
-		amountsSpent[assetID] = newAmountSpent
-
-		// add the new input to the array
-		ins = append(ins, &avax.TransferableInput{
-			UTXOID: utxo.UTXOID,
-			Asset:  avax.Asset{ID: assetID},
-			In:     input,
+		coins[assetID] = append(coins[assetID], &Coin{
+			UTXO:    utxo,
+			Input:   input,
+			Signers: inputSigners,
 		})
-		// add the required keys to the array
-		keys = append(keys, signers)
 	}
 
-	for asset, amount := range amounts {
-		if amountsSpent[asset] < amount {
-			return nil, nil, nil, fmt.Errorf("want to spend %d of asset %s but only have %d",
+	assetIDs := make([]ids.ID, 0, len(amounts))
+	for assetID, amount := range amounts {
+		if amount == 0 {
+			continue
+		}
+		if available := sumCoins(coins[assetID]); available < amount {
+			err := fmt.Errorf("%w: want to spend %d of asset %s but only have %d",
+				errInsufficientFunds,
 				amount,
-				asset,
-				amountsSpent[asset],
+				assetID,
+				available,
 			)
+			if locked[assetID] > 0 {
+				err = fmt.Errorf("%w, and %d more that is locked", err, locked[assetID])
+			}
+			return nil, nil, nil, err
 		}
+		assetIDs = append(assetIDs, assetID)
 	}
+	ids.SortIDs(assetIDs)
 
-	avax.SortTransferableInputsWithSigners(ins, keys)
-	return amountsSpent, ins, keys, nil
+	amountsSpent := make(map[ids.ID]uint64, len(amounts))
+	ins := []*avax.TransferableInput{}
+	signers := [][]ids.ShortID{}
+	for _, assetID := range assetIDs {
+		remainingInputs := 0
+		if maxInputs > 0 {
+			remainingInputs = maxInputs - len(ins)
+			if remainingInputs <= 0 {
+				return nil, nil, nil, fmt.Errorf("%w: no inputs left for asset %s", errTooManyInputs, assetID)
+			}
+		}
+
+		selected, err := selector.Select(coins[assetID], amounts[assetID], remainingInputs)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("couldn't select inputs of asset %s: %w", assetID, err)
+		}
+		for _, coin := range selected {
+			newAmountSpent, err := math.Add64(amountsSpent[assetID], coin.Amount())
+			if err != nil {
+				// there was an error calculating the consumed amount, just error
+				return nil, nil, nil, errSpendOverflow
+			}
+			amountsSpent[assetID] = newAmountSpent
+
+			// add the new input to the array
+			ins = append(ins, &avax.TransferableInput{
+				UTXOID: coin.UTXO.UTXOID,
+				Asset:  avax.Asset{ID: assetID},
+				In:     coin.Input,
+			})
+			// add the required signers to the array
+			signers = append(signers, coin.Signers)
+		}
+	}
+
+	sortInputsWithSigners(ins, signers)
+	return amountsSpent, ins, signers, nil
 }
 
+// BuildOutputs sends [numUTXOsPerAddress] outputs of [amountPerUTXO] to each
+// of [addresses], batched by PackOutputs under [maxOutputsSize] with room for
+// change owned by up to [changeAddrs] addresses.
 func BuildOutputs(
 	addresses []ids.ShortID,
 	numUTXOsPerAddress int,
 	assetID ids.ID,
 	amountPerUTXO uint64,
+	maxOutputsSize int,
+	changeAddrs int,
 ) ([][]*avax.TransferableOutput, error) {
 	ids.SortShortIDs(addresses)
 	if !ids.IsSortedAndUniqueShortIDs(addresses) {
 		return nil, errDuplicatedAddresses
 	}
 
-	var (
-		outs               [][]*avax.TransferableOutput
-		currentSpentAmount uint64
-		currentOuts        []*avax.TransferableOutput
-	)
+	outs := make([]*avax.TransferableOutput, 0, len(addresses)*numUTXOsPerAddress)
 	for _, addr := range addresses {
-		addrStr, _ := formatting.FormatAddress("P", "local", addr[:])
-		log.Println(addrStr, addr)
-
 		for i := 0; i < numUTXOsPerAddress; i++ {
-			newSpentAmount, err := math.Add64(currentSpentAmount, amountPerUTXO)
-			if err != nil || len(currentOuts) >= maxOutputsPerTx {
-				outs = append(outs, currentOuts)
-				currentSpentAmount = amountPerUTXO
-				currentOuts = nil
-			} else {
-				currentSpentAmount = newSpentAmount
-			}
-
-			currentOuts = append(currentOuts, &avax.TransferableOutput{
+			outs = append(outs, &avax.TransferableOutput{
 				Asset: avax.Asset{ID: assetID},
 				Out: &secp256k1fx.TransferOutput{
 					Amt: amountPerUTXO,
@@ -463,8 +456,5 @@ func BuildOutputs(
 			})
 		}
 	}
-	if len(currentOuts) > 0 {
-		outs = append(outs, currentOuts)
-	}
-	return outs, nil
+	return PackOutputs(outs, maxOutputsSize, changeAddrs)
 }
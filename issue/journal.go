@@ -0,0 +1,202 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+var (
+	errJournalMismatch = errors.New("journal was written for different outputs")
+	errInvalidChunk    = errors.New("journal references an unknown batch")
+	errStillProcessing = errors.New("transaction from a previous run is still undecided")
+)
+
+// journalHeader is the first line of a journal and identifies the batches of
+// outputs that it tracks
+type journalHeader struct {
+	OutputsHash ids.ID `json:"outputsHash"`
+	NumChunks   int    `json:"numChunks"`
+}
+
+// journalEntry records the status of the transaction sending a batch
+type journalEntry struct {
+	Chunk int    `json:"chunk"`
+	TxID  ids.ID `json:"txID"`
+	// TxBytes are the signed transaction, which is only recorded before it
+	// is issued
+	TxBytes []byte         `json:"txBytes,omitempty"`
+	Status  choices.Status `json:"status"`
+}
+
+// Journal durably records which batches of outputs have been sent, so that an
+// interrupted Send can be resumed without sending any batch twice.
+//
+// An entry with the Processing status, and the signed transaction, is written
+// before a transaction is issued, and another with its final status once it
+// has been decided. A transaction that wasn't decided is issued again when the
+// journal is resumed, rather than being rebuilt, so that it can't be paid for
+// twice.
+type Journal struct {
+	lock    sync.Mutex
+	file    *os.File
+	entries map[int]journalEntry
+}
+
+// OpenJournal opens the journal at [path] that tracks the sending of [txOuts],
+// creating it if it doesn't exist. An existing journal must have been written
+// for the same [txOuts].
+func OpenJournal(path string, txOuts [][]*avax.TransferableOutput) (*Journal, error) {
+	outputsBytes, err := platformvm.Codec.Marshal(txs.CodecVersion, txOuts)
+	if err != nil {
+		return nil, err
+	}
+	header := journalHeader{
+		OutputsHash: hashing.ComputeHash256Array(outputsBytes),
+		NumChunks:   len(txOuts),
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		file:    file,
+		entries: make(map[int]journalEntry),
+	}
+	if err := j.load(header); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("journal %q: %w", path, err)
+	}
+	return j, nil
+}
+
+// load reads the existing entries or, if the journal is empty, writes
+// [header]. A partially written final line is discarded.
+func (j *Journal) load(header journalHeader) error {
+	journalBytes, err := os.ReadFile(j.file.Name())
+	if err != nil {
+		return err
+	}
+	complete := journalBytes[:bytes.LastIndexByte(journalBytes, '\n')+1]
+	if err := j.file.Truncate(int64(len(complete))); err != nil {
+		return err
+	}
+	if _, err := j.file.Seek(int64(len(complete)), io.SeekStart); err != nil {
+		return err
+	}
+
+	if len(complete) == 0 {
+		return j.write(&header)
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(complete, []byte{'\n'}), []byte{'\n'})
+	existingHeader := journalHeader{}
+	if err := json.Unmarshal(lines[0], &existingHeader); err != nil {
+		return err
+	}
+	if existingHeader != header {
+		return errJournalMismatch
+	}
+	for i, line := range lines[1:] {
+		entry := journalEntry{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("line %d: %w", i+2, err)
+		}
+		if entry.Chunk < 0 || entry.Chunk >= header.NumChunks {
+			return fmt.Errorf("%w %d on line %d", errInvalidChunk, entry.Chunk, i+2)
+		}
+		j.entries[entry.Chunk] = entry
+	}
+	return nil
+}
+
+// resume determines which batches still need to be sent. Transactions that
+// may have been issued by a previous run are settled with [settle], which is
+// given their ID and signed bytes and returns their status. Only the batches
+// of transactions that were rejected are sent again. If a transaction is
+// still undecided, an error is returned, as rebuilding its batch could spend
+// other UTXOs and pay for the batch twice.
+func (j *Journal) resume(
+	numChunks int,
+	settle func(txID ids.ID, txBytes []byte) (choices.Status, error),
+) ([]int, error) {
+	var unsent []int
+	for chunk := 0; chunk < numChunks; chunk++ {
+		entry, ok := j.entries[chunk]
+		if !ok {
+			unsent = append(unsent, chunk)
+			continue
+		}
+
+		status := entry.Status
+		if !status.Decided() {
+			var err error
+			status, err = settle(entry.TxID, entry.TxBytes)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't settle transaction %d (%s): %w", chunk, entry.TxID, err)
+			}
+			if !status.Decided() {
+				return nil, fmt.Errorf("%w: transaction %d (%s) has status %s", errStillProcessing, chunk, entry.TxID, status)
+			}
+			if err := j.record(chunk, entry.TxID, nil, status); err != nil {
+				return nil, err
+			}
+		}
+		if status != choices.Accepted {
+			unsent = append(unsent, chunk)
+		}
+	}
+	return unsent, nil
+}
+
+// record durably writes the [status] of [txID], which sends batch [chunk].
+// [txBytes] should be provided before the transaction is issued.
+func (j *Journal) record(chunk int, txID ids.ID, txBytes []byte, status choices.Status) error {
+	entry := journalEntry{
+		Chunk:   chunk,
+		TxID:    txID,
+		TxBytes: txBytes,
+		Status:  status,
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if err := j.write(&entry); err != nil {
+		return fmt.Errorf("couldn't write journal: %w", err)
+	}
+	j.entries[chunk] = entry
+	return nil
+}
+
+func (j *Journal) write(line interface{}) error {
+	lineBytes, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(lineBytes, '\n')); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
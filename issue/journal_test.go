@@ -0,0 +1,153 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+)
+
+// testChain is an issuer and confirmer that reports the statuses in
+// [statuses] and records the transactions issued to it
+type testChain struct {
+	statuses map[ids.ID][]choices.Status
+	issued   [][]byte
+}
+
+func (c *testChain) IssueTx(txBytes []byte) (ids.ID, error) {
+	c.issued = append(c.issued, txBytes)
+	return ids.Empty, nil
+}
+
+func (c *testChain) Confirm(txID ids.ID) (choices.Status, error) {
+	statuses := c.statuses[txID]
+	if len(statuses) == 0 {
+		return choices.Unknown, nil
+	}
+	status := statuses[0]
+	if len(statuses) > 1 {
+		c.statuses[txID] = statuses[1:]
+	}
+	return status, nil
+}
+
+func newTestTxOuts(numChunks int) [][]*avax.TransferableOutput {
+	return make([][]*avax.TransferableOutput, numChunks)
+}
+
+func TestJournalResume(t *testing.T) {
+	tests := []struct {
+		name           string
+		txBytes        []byte
+		statuses       []choices.Status
+		expectedUnsent bool
+		expectedIssue  bool
+		expectedErr    error
+	}{
+		{
+			name:     "accepted",
+			txBytes:  []byte{1},
+			statuses: []choices.Status{choices.Accepted},
+		},
+		{
+			name:           "rejected",
+			txBytes:        []byte{1},
+			statuses:       []choices.Status{choices.Rejected},
+			expectedUnsent: true,
+		},
+		{
+			name:          "unknown is reissued",
+			txBytes:       []byte{1},
+			statuses:      []choices.Status{choices.Unknown, choices.Accepted},
+			expectedIssue: true,
+		},
+		{
+			name:          "processing is reissued",
+			txBytes:       []byte{1},
+			statuses:      []choices.Status{choices.Processing, choices.Accepted},
+			expectedIssue: true,
+		},
+		{
+			name:          "still undecided",
+			txBytes:       []byte{1},
+			statuses:      []choices.Status{choices.Processing},
+			expectedIssue: true,
+			expectedErr:   errStillProcessing,
+		},
+		{
+			name:        "undecided without bytes",
+			statuses:    []choices.Status{choices.Unknown},
+			expectedErr: errStillProcessing,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "journal")
+			txOuts := newTestTxOuts(2)
+			txID := ids.GenerateTestID()
+
+			j, err := OpenJournal(path, txOuts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := j.record(0, txID, test.txBytes, choices.Processing); err != nil {
+				t.Fatal(err)
+			}
+			if err := j.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			j, err = OpenJournal(path, txOuts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer j.Close()
+
+			chain := &testChain{statuses: map[ids.ID][]choices.Status{txID: test.statuses}}
+			p := &Pipeline{Issuer: chain, Confirmer: chain}
+			unsent, err := j.resume(len(txOuts), p.settle)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected error %v, got %v", test.expectedErr, err)
+			}
+			if test.expectedIssue != (len(chain.issued) == 1) {
+				t.Fatalf("expected reissue %t, issued %d transactions", test.expectedIssue, len(chain.issued))
+			}
+			if test.expectedIssue && !bytes.Equal(chain.issued[0], test.txBytes) {
+				t.Fatalf("expected the journaled bytes %x to be reissued, got %x", test.txBytes, chain.issued[0])
+			}
+			if err != nil {
+				return
+			}
+
+			expectedUnsent := []int{1}
+			if test.expectedUnsent {
+				expectedUnsent = []int{0, 1}
+			}
+			if len(unsent) != len(expectedUnsent) || unsent[0] != expectedUnsent[0] {
+				t.Fatalf("expected unsent batches %v, got %v", expectedUnsent, unsent)
+			}
+		})
+	}
+}
+
+func TestJournalMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := OpenJournal(path, newTestTxOuts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenJournal(path, newTestTxOuts(3)); !errors.Is(err, errJournalMismatch) {
+		t.Fatalf("expected %v, got %v", errJournalMismatch, err)
+	}
+}
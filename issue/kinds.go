@@ -0,0 +1,110 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+var (
+	_ TxKind = &BaseTxKind{}
+	_ TxKind = &ExportTxKind{}
+	_ TxKind = &ImportTxKind{}
+)
+
+// BaseTxKind builds X-chain transactions that send the outputs on the
+// X-chain
+type BaseTxKind struct {
+	NetworkID uint32
+	ChainID   ids.ID
+}
+
+func (k *BaseTxKind) Build(
+	outs []*avax.TransferableOutput,
+	change []*avax.TransferableOutput,
+	ins []*avax.TransferableInput,
+) (txs.UnsignedTx, []*avax.TransferableOutput, error) {
+	allOuts := make([]*avax.TransferableOutput, 0, len(outs)+len(change))
+	allOuts = append(allOuts, outs...)
+	allOuts = append(allOuts, change...)
+	avax.SortTransferableOutputs(allOuts, c)
+
+	tx, err := txs.NewAVMTx(&avm.BaseTx{BaseTx: avax.BaseTx{
+		NetworkID:    k.NetworkID,
+		BlockchainID: k.ChainID,
+		Outs:         allOuts,
+		Ins:          ins,
+	}})
+	return tx, allOuts, err
+}
+
+// ExportTxKind builds X-chain transactions that export the outputs to
+// [DestinationChainID]. Change remains on the X-chain.
+type ExportTxKind struct {
+	NetworkID          uint32
+	ChainID            ids.ID
+	DestinationChainID ids.ID
+}
+
+func (k *ExportTxKind) Build(
+	outs []*avax.TransferableOutput,
+	change []*avax.TransferableOutput,
+	ins []*avax.TransferableInput,
+) (txs.UnsignedTx, []*avax.TransferableOutput, error) {
+	exportedOuts := make([]*avax.TransferableOutput, len(outs))
+	copy(exportedOuts, outs)
+	avax.SortTransferableOutputs(exportedOuts, c)
+	avax.SortTransferableOutputs(change, c)
+
+	tx, err := txs.NewAVMTx(&avm.ExportTx{
+		BaseTx: avm.BaseTx{BaseTx: avax.BaseTx{
+			NetworkID:    k.NetworkID,
+			BlockchainID: k.ChainID,
+			Outs:         change,
+			Ins:          ins,
+		}},
+		DestinationChain: k.DestinationChainID,
+		ExportedOuts:     exportedOuts,
+	})
+	// Only the change remains on the X-chain, the exported outputs are
+	// produced in the destination chain's shared memory.
+	return tx, change, err
+}
+
+// ImportTxKind builds P-chain transactions that import UTXOs from
+// [SourceChainID] to fund the outputs. Change is also sent on the P-chain.
+type ImportTxKind struct {
+	NetworkID     uint32
+	ChainID       ids.ID
+	SourceChainID ids.ID
+}
+
+func (k *ImportTxKind) Build(
+	outs []*avax.TransferableOutput,
+	change []*avax.TransferableOutput,
+	ins []*avax.TransferableInput,
+) (txs.UnsignedTx, []*avax.TransferableOutput, error) {
+	allOuts := make([]*avax.TransferableOutput, 0, len(outs)+len(change))
+	allOuts = append(allOuts, outs...)
+	allOuts = append(allOuts, change...)
+	avax.SortTransferableOutputs(allOuts, platformvm.Codec)
+
+	tx, err := txs.NewPlatformTx(&platformvm.UnsignedImportTx{
+		BaseTx: platformvm.BaseTx{BaseTx: avax.BaseTx{
+			NetworkID:    k.NetworkID,
+			BlockchainID: k.ChainID,
+			Outs:         allOuts,
+		}},
+		SourceChain:    k.SourceChainID,
+		ImportedInputs: ins,
+	})
+	// The outputs are produced on the P-chain rather than in the shared
+	// memory that the inputs were imported from.
+	return tx, nil, err
+}
@@ -0,0 +1,287 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/utils/math"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+const (
+	// avaxAlias may be used in place of the AVAX asset ID in a manifest
+	avaxAlias = "AVAX"
+
+	// csvAddressSeparator separates the owners of an output in a CSV manifest
+	csvAddressSeparator = ";"
+)
+
+var (
+	errNoManifestAddresses = errors.New("output has no addresses")
+	errZeroAmount          = errors.New("output has no amount")
+	errInvalidThreshold    = errors.New("threshold must be between 1 and the number of addresses")
+	errWrongHRP            = errors.New("address is for a different network")
+	errDuplicateOutput     = errors.New("duplicate output")
+	errMissingColumn       = errors.New("missing column")
+	errUnknownColumn       = errors.New("unknown column")
+)
+
+// ManifestEntry is one output of an airdrop manifest
+type ManifestEntry struct {
+	// Addresses are the bech32 addresses, with or without a chain prefix, that
+	// own the output
+	Addresses []string `json:"addresses"`
+	// Asset is the ID of the asset to send, or "AVAX". Defaults to AVAX.
+	Asset  string `json:"asset,omitempty"`
+	Amount uint64 `json:"amount"`
+	// Locktime is the unix time until which the output can't be spent
+	Locktime uint64 `json:"locktime,omitempty"`
+	// Threshold is the number of [Addresses] that must sign to spend the
+	// output. Defaults to 1.
+	Threshold uint32 `json:"threshold,omitempty"`
+}
+
+// ReadManifest reads the manifest at [path]. Files ending in ".csv" are read
+// as CSV, with a header naming the "address", "asset", "amount", "locktime",
+// and "threshold" columns, of which only "address" and "amount" are required.
+// Multiple owners are separated by ";". Any other file is read as a JSON list
+// of ManifestEntry.
+func ReadManifest(path string) ([]ManifestEntry, error) {
+	manifestBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ManifestEntry
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		entries, err = parseCSVManifest(manifestBytes)
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(manifestBytes))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&entries)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse manifest %q: %w", path, err)
+	}
+	return entries, nil
+}
+
+func parseCSVManifest(manifestBytes []byte) ([]ManifestEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(manifestBytes))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "address", "asset", "amount", "locktime", "threshold":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("%w %q", errUnknownColumn, name)
+		}
+	}
+	for _, name := range []string{"address", "amount"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w %q", errMissingColumn, name)
+		}
+	}
+
+	var entries []ManifestEntry
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry, err := parseCSVEntry(columns, record)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func parseCSVEntry(columns map[string]int, record []string) (ManifestEntry, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	entry := ManifestEntry{
+		Asset: field("asset"),
+	}
+	for _, addr := range strings.Split(field("address"), csvAddressSeparator) {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			entry.Addresses = append(entry.Addresses, addr)
+		}
+	}
+
+	var err error
+	entry.Amount, err = strconv.ParseUint(field("amount"), 10, 64)
+	if err != nil {
+		return entry, fmt.Errorf("couldn't parse amount: %w", err)
+	}
+	if locktime := field("locktime"); locktime != "" {
+		entry.Locktime, err = strconv.ParseUint(locktime, 10, 64)
+		if err != nil {
+			return entry, fmt.Errorf("couldn't parse locktime: %w", err)
+		}
+	}
+	if threshold := field("threshold"); threshold != "" {
+		parsedThreshold, err := strconv.ParseUint(threshold, 10, 32)
+		if err != nil {
+			return entry, fmt.Errorf("couldn't parse threshold: %w", err)
+		}
+		entry.Threshold = uint32(parsedThreshold)
+	}
+	return entry, nil
+}
+
+// ManifestOutputs converts [entries] into outputs on the network [networkID].
+// Addresses for any other network are rejected. Outputs with the same asset
+// and owners are summed if [mergeDuplicates] is set, and rejected otherwise.
+func ManifestOutputs(
+	entries []ManifestEntry,
+	networkID uint32,
+	avaxAssetID ids.ID,
+	mergeDuplicates bool,
+) ([]*avax.TransferableOutput, error) {
+	hrp := constants.GetHRP(networkID)
+
+	var (
+		outs    []*avax.TransferableOutput
+		indices = make(map[string]int, len(entries))
+	)
+	for i, entry := range entries {
+		out, err := manifestOutput(entry, hrp, avaxAssetID)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+
+		key, err := outputKey(out)
+		if err != nil {
+			return nil, err
+		}
+		index, ok := indices[key]
+		if !ok {
+			indices[key] = len(outs)
+			outs = append(outs, out)
+			continue
+		}
+		if !mergeDuplicates {
+			return nil, fmt.Errorf("entry %d: %w of entry %d", i+1, errDuplicateOutput, index+1)
+		}
+
+		merged := outs[index].Out.(*secp256k1fx.TransferOutput)
+		merged.Amt, err = math.Add64(merged.Amt, entry.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: merged amount overflows uint64", i+1)
+		}
+	}
+	return outs, nil
+}
+
+// outputKey identifies the asset and owners of [out]
+func outputKey(out *avax.TransferableOutput) (string, error) {
+	owners := &out.Out.(*secp256k1fx.TransferOutput).OutputOwners
+	ownersBytes, err := c.Marshal(txs.CodecVersion, owners)
+	if err != nil {
+		return "", err
+	}
+	assetID := out.AssetID()
+	return string(assetID[:]) + string(ownersBytes), nil
+}
+
+func manifestOutput(entry ManifestEntry, hrp string, avaxAssetID ids.ID) (*avax.TransferableOutput, error) {
+	if len(entry.Addresses) == 0 {
+		return nil, errNoManifestAddresses
+	}
+	if entry.Amount == 0 {
+		return nil, errZeroAmount
+	}
+
+	assetID := avaxAssetID
+	if entry.Asset != "" && !strings.EqualFold(entry.Asset, avaxAlias) {
+		var err error
+		assetID, err = ids.FromString(entry.Asset)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse asset ID: %w", err)
+		}
+	}
+
+	addrs := make([]ids.ShortID, len(entry.Addresses))
+	for i, addrStr := range entry.Addresses {
+		addr, err := parseManifestAddress(addrStr, hrp)
+		if err != nil {
+			return nil, fmt.Errorf("address %q: %w", addrStr, err)
+		}
+		addrs[i] = addr
+	}
+	ids.SortShortIDs(addrs)
+	if !ids.IsSortedAndUniqueShortIDs(addrs) {
+		return nil, errDuplicatedAddresses
+	}
+
+	threshold := entry.Threshold
+	if threshold == 0 {
+		threshold = 1
+	}
+	if int(threshold) > len(addrs) {
+		return nil, errInvalidThreshold
+	}
+
+	return &avax.TransferableOutput{
+		Asset: avax.Asset{ID: assetID},
+		Out: &secp256k1fx.TransferOutput{
+			Amt: entry.Amount,
+			OutputOwners: secp256k1fx.OutputOwners{
+				Locktime:  entry.Locktime,
+				Threshold: threshold,
+				Addrs:     addrs,
+			},
+		},
+	}, nil
+}
+
+// parseManifestAddress parses [addrStr], which may have a chain prefix, and
+// requires it to have the human readable part [hrp]
+func parseManifestAddress(addrStr string, hrp string) (ids.ShortID, error) {
+	bech32 := addrStr
+	if i := strings.Index(addrStr, "-"); i >= 0 {
+		bech32 = addrStr[i+1:]
+	}
+	addrHRP, addrBytes, err := formatting.ParseBech32(bech32)
+	if err != nil {
+		return ids.ShortID{}, err
+	}
+	if addrHRP != hrp {
+		return ids.ShortID{}, fmt.Errorf("%w: expected %q but got %q", errWrongHRP, hrp, addrHRP)
+	}
+	return ids.ToShortID(addrBytes)
+}
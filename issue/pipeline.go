@@ -0,0 +1,584 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+const (
+	// defaultRetries is the number of times a failed RPC is retried
+	defaultRetries = 5
+	initialBackoff = 250 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+
+	// defaultMaxPending is the number of transactions that each builder may
+	// have issued but not yet accepted if [Pipeline.MaxPending] isn't set
+	defaultMaxPending = 8
+)
+
+var errNotAccepted = errors.New("transaction wasn't accepted")
+
+// Tx is a signed transaction that is ready to be issued
+type Tx struct {
+	ID    ids.ID
+	Bytes []byte
+	// Inputs are the IDs of the UTXOs consumed by the transaction
+	Inputs []ids.ID
+	// UTXOs are the outputs that the transaction produces on the chain that
+	// its inputs were spent from, so that later transactions may spend them
+	UTXOs []*avax.UTXO
+}
+
+// newTx returns the transaction [txID] that consumes [ins] and produces
+// [outs], in order, on the chain that [ins] were spent from.
+func newTx(
+	txID ids.ID,
+	txBytes []byte,
+	ins []*avax.TransferableInput,
+	outs []*avax.TransferableOutput,
+) *Tx {
+	tx := &Tx{
+		ID:     txID,
+		Bytes:  txBytes,
+		Inputs: make([]ids.ID, len(ins)),
+		UTXOs:  make([]*avax.UTXO, len(outs)),
+	}
+	for i, in := range ins {
+		tx.Inputs[i] = in.InputID()
+	}
+	for i, out := range outs {
+		tx.UTXOs[i] = &avax.UTXO{
+			UTXOID: avax.UTXOID{
+				TxID:        txID,
+				OutputIndex: uint32(i),
+			},
+			Asset: out.Asset,
+			Out:   out.Out,
+		}
+	}
+	return tx
+}
+
+// Builder creates the signed transaction that produces [outs]
+type Builder interface {
+	Build(outs []*avax.TransferableOutput) (*Tx, error)
+
+	// Issued is called once [tx] has been issued so that later transactions
+	// don't spend its inputs again and may spend its outputs.
+	Issued(tx *Tx)
+}
+
+// Issuer submits a signed transaction to its chain. Both *avm.Client and
+// *platformvm.Client implement it.
+type Issuer interface {
+	IssueTx(txBytes []byte) (ids.ID, error)
+}
+
+// Confirmer waits for an issued transaction to be decided
+type Confirmer interface {
+	// Confirm returns the status of [txID] once it has been decided, or its
+	// latest status if it wasn't decided in time.
+	Confirm(txID ids.ID) (choices.Status, error)
+}
+
+// UTXOSource provides the UTXOs that the next transaction may spend
+type UTXOSource interface {
+	// UTXOs returns the spendable UTXOs. The returned map must not be
+	// modified.
+	UTXOs() (map[ids.ID]*avax.UTXO, error)
+
+	// Issued is called once [tx] has been issued
+	Issued(tx *Tx)
+}
+
+// TxKind assembles a specific type of unsigned transaction. [outs] are the
+// outputs being sent and [change] are the outputs returning the excess of
+// [ins] to the spender. The outputs that the transaction produces on the chain
+// that [ins] are spent from are returned in the order they are indexed.
+type TxKind interface {
+	Build(
+		outs []*avax.TransferableOutput,
+		change []*avax.TransferableOutput,
+		ins []*avax.TransferableInput,
+	) (txs.UnsignedTx, []*avax.TransferableOutput, error)
+}
+
+// SpendBuilder builds transactions that fund their outputs, and fee, from
+// the UTXOs of [Signer] and return any excess as chosen by [Change].
+type SpendBuilder struct {
+	Source     UTXOSource
+	Kind       TxKind
+	Signer     signer.Signer
+	FeeAssetID ids.ID
+	Fee        uint64
+	// Selector chooses the UTXOs spent by each transaction. Defaults to
+	// LargestFirst.
+	Selector CoinSelector
+	// MaxInputs, if positive, is the most inputs a transaction may have
+	MaxInputs int
+	// Change chooses the owners of each transaction's change. Defaults to the
+	// signer's first address.
+	Change ChangePolicy
+}
+
+// unsignedSpend is an unsigned transaction built by a SpendBuilder
+type unsignedSpend struct {
+	tx  txs.UnsignedTx
+	ins []*avax.TransferableInput
+	// signers are the addresses that must sign each of the [ins]
+	signers [][]ids.ShortID
+	// consumed are the UTXOs spent by each of the [ins]
+	consumed []*avax.UTXO
+	// produced are the outputs of [tx] on the chain that [ins] are spent from
+	produced []*avax.TransferableOutput
+	// change are the owners of the change of [tx], or nil if it has none
+	change *secp256k1fx.OutputOwners
+}
+
+func (b *SpendBuilder) Build(outs []*avax.TransferableOutput) (*Tx, error) {
+	spend, err := b.build(outs)
+	if err != nil {
+		return nil, err
+	}
+	txBytes, err := signer.SignTx(spend.tx, b.Signer, spend.signers)
+	if err != nil {
+		discardChange(b.Change, spend.change)
+		return nil, err
+	}
+	txID := hashing.ComputeHash256Array(txBytes)
+	return newTx(txID, txBytes, spend.ins, spend.produced), nil
+}
+
+// BuildUnsigned builds the transaction that produces [outs] without signing
+// it. The UTXOs that it consumes, which are needed to sign it, are returned
+// along with it.
+func (b *SpendBuilder) BuildUnsigned(outs []*avax.TransferableOutput) (txs.UnsignedTx, []*avax.UTXO, error) {
+	spend, err := b.build(outs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return spend.tx, spend.consumed, nil
+}
+
+func (b *SpendBuilder) build(outs []*avax.TransferableOutput) (*unsignedSpend, error) {
+	cost, err := GetCost(outs, b.FeeAssetID, b.Fee)
+	if err != nil {
+		return nil, err
+	}
+
+	utxos, err := b.Source.UTXOs()
+	if err != nil {
+		return nil, err
+	}
+
+	spent, ins, signers, err := BuildInputs(utxos, signer.AddressSet(b.Signer), cost, b.Selector, b.MaxInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	consumed := make([]*avax.UTXO, len(ins))
+	for i, in := range ins {
+		consumed[i] = utxos[in.InputID()]
+	}
+
+	changeOwners, err := changeOwners(b.Change, b.Signer, consumed)
+	if err != nil {
+		return nil, err
+	}
+	change := GetChangeOutputs(changeOwners, cost, spent)
+	if len(change) == 0 {
+		// The owners aren't paid, so they may be chosen again
+		discardChange(b.Change, changeOwners)
+		changeOwners = nil
+	}
+	tx, produced, err := b.Kind.Build(outs, change, ins)
+	if err == nil {
+		err = checkTxSize(tx, signers)
+	}
+	if err != nil {
+		discardChange(b.Change, changeOwners)
+		return nil, err
+	}
+	return &unsignedSpend{
+		tx:       tx,
+		ins:      ins,
+		signers:  signers,
+		consumed: consumed,
+		produced: produced,
+		change:   changeOwners,
+	}, nil
+}
+
+func (b *SpendBuilder) Issued(tx *Tx) {
+	b.Source.Issued(tx)
+	changeIssued(b.Change, tx)
+}
+
+// Pipeline issues one transaction per batch of outputs. Each builder issues
+// its next transaction as soon as its previous one has been issued, so that it
+// may spend the previous transaction's change before it is accepted. The
+// transactions are confirmed in the background.
+type Pipeline struct {
+	// Builders are run concurrently, so they must not spend the same UTXOs
+	Builders  []Builder
+	Issuer    Issuer
+	Confirmer Confirmer
+	// Retries is the number of times that a failed call to the issuer or the
+	// confirmer is retried, with exponential backoff, before giving up.
+	// Reissuing a transaction can't double spend, as its ID is fixed by its
+	// bytes.
+	Retries int
+	// MaxPending is the number of transactions that each builder may have
+	// issued but not yet accepted. Defaults to 8.
+	MaxPending int
+	// Journal, if set, records the progress of Send so that it can be resumed
+	// after being interrupted.
+	Journal *Journal
+}
+
+// Send issues a transaction for each of the [txOuts], in no particular order.
+// Once any transaction fails to be built, issued, or accepted, no more are
+// started and the first failure is returned after the transactions in flight
+// are decided. If the pipeline has a journal, the batches that it records as
+// sent are skipped. Batches that no builder can fund on its own are reported
+// once the others have been sent.
+func (p *Pipeline) Send(txOuts [][]*avax.TransferableOutput) error {
+	unsent := make([]int, len(txOuts))
+	for i := range unsent {
+		unsent[i] = i
+	}
+	if p.Journal != nil {
+		var err error
+		unsent, err = p.Journal.resume(len(txOuts), p.settle)
+		if err != nil {
+			return err
+		}
+		if skipped := len(txOuts) - len(unsent); skipped > 0 {
+			log.Printf("resuming with %d of %d transactions already sent", skipped, len(txOuts))
+		}
+	}
+
+	// Batches are taken from the end of the slice, so reverse it to send them
+	// in order.
+	for i, j := 0, len(unsent)-1; i < j; i, j = i+1, j-1 {
+		unsent[i], unsent[j] = unsent[j], unsent[i]
+	}
+	s := &sendState{
+		txOuts: txOuts,
+		unsent: unsent,
+		start:  time.Now(),
+	}
+	s.cond = sync.NewCond(&s.lock)
+
+	var wg sync.WaitGroup
+	for _, b := range p.Builders {
+		wg.Add(1)
+		go func(b Builder) {
+			defer wg.Done()
+			p.run(b, s)
+		}(b)
+	}
+	wg.Wait()
+
+	if s.err != nil {
+		return s.err
+	}
+	if numUnsent := len(s.unsent); numUnsent > 0 {
+		// Each batch is funded by a single builder, so batches that need more
+		// than any one builder holds can't be sent even if the builders hold
+		// enough together.
+		i := s.unsent[len(s.unsent)-1]
+		return fmt.Errorf("%d of %d transactions couldn't be funded by the UTXOs of any single one of the %d builders, such as transaction %d: %w",
+			numUnsent,
+			len(txOuts),
+			len(p.Builders),
+			i,
+			s.fundingErrs[i],
+		)
+	}
+	log.Printf("sent %d outputs in %d transactions in %s - %.1f outputs/s", s.numSent, s.numTxs, time.Since(s.start).Round(time.Millisecond), s.rate())
+	return nil
+}
+
+// run sends batches with [b] until there are none left, [b] runs out of
+// funds, or a transaction fails. It returns once the transactions that it
+// issued have been decided.
+func (p *Pipeline) run(b Builder, s *sendState) {
+	maxPending := p.MaxPending
+	if maxPending <= 0 {
+		maxPending = defaultMaxPending
+	}
+	// pending holds a value for each transaction that is being built or
+	// hasn't been accepted yet
+	pending := make(chan struct{}, maxPending)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		pending <- struct{}{}
+		i, ok := s.take()
+		if !ok {
+			<-pending
+			return
+		}
+
+		outs := s.txOuts[i]
+		tx, err := b.Build(outs)
+		if errors.Is(err, errInsufficientFunds) {
+			// Leave the batch to the builders that still have funds
+			<-pending
+			s.requeue(i, err)
+			return
+		}
+		if err != nil {
+			<-pending
+			s.fail(fmt.Errorf("couldn't build transaction %d: %w", i, err))
+			return
+		}
+
+		if err := p.issue(i, b, tx); err != nil {
+			<-pending
+			s.fail(err)
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-pending }()
+
+			if err := p.accept(i, tx); err != nil {
+				s.fail(err)
+				return
+			}
+			numSent, rate := s.sent(len(outs))
+			log.Printf("%s - %s - %d outputs - %d sent - %.1f outputs/s", tx.ID, choices.Accepted, len(outs), numSent, rate)
+		}()
+	}
+}
+
+// issue issues [tx], which sends batch [i], and tells [b] that it was issued
+func (p *Pipeline) issue(i int, b Builder, tx *Tx) error {
+	if err := p.record(i, tx.ID, tx.Bytes, choices.Processing); err != nil {
+		return err
+	}
+
+	if err := p.issueTx(tx.ID, tx.Bytes); err != nil {
+		return fmt.Errorf("couldn't issue transaction %d (%s): %w", i, tx.ID, err)
+	}
+	b.Issued(tx)
+	return nil
+}
+
+// accept waits for [tx], which sends batch [i], to be accepted
+func (p *Pipeline) accept(i int, tx *Tx) error {
+	status, err := p.confirm(tx.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't confirm transaction %d (%s): %w", i, tx.ID, err)
+	}
+	if status.Decided() {
+		if err := p.record(i, tx.ID, nil, status); err != nil {
+			return err
+		}
+	}
+	if status != choices.Accepted {
+		return fmt.Errorf("%w: transaction %d (%s) has status %s", errNotAccepted, i, tx.ID, status)
+	}
+	return nil
+}
+
+// issueTx issues [txBytes], whose ID is [txID], retrying as [p.retry] does. A
+// failed attempt, such as one that timed out, may still have been received by
+// the node, after which every attempt would fail as the inputs are already
+// spent. So, before each retry, the transaction isn't issued again if the
+// node reports it as processing or accepted.
+func (p *Pipeline) issueTx(txID ids.ID, txBytes []byte) error {
+	attempted := false
+	return p.retry(func() error {
+		if attempted {
+			status, err := p.status(txID)
+			if err == nil && (status == choices.Processing || status == choices.Accepted) {
+				return nil
+			}
+		}
+		attempted = true
+		_, err := p.Issuer.IssueTx(txBytes)
+		return err
+	})
+}
+
+// statusChecker is implemented by confirmers, such as ChainClient, that can
+// report the status of a transaction without waiting for it to be decided
+type statusChecker interface {
+	Status(txID ids.ID) (choices.Status, error)
+}
+
+// status returns the current status of [txID]
+func (p *Pipeline) status(txID ids.ID) (choices.Status, error) {
+	if checker, ok := p.Confirmer.(statusChecker); ok {
+		return checker.Status(txID)
+	}
+	return p.Confirmer.Confirm(txID)
+}
+
+func (p *Pipeline) confirm(txID ids.ID) (choices.Status, error) {
+	var status choices.Status
+	err := p.retry(func() (err error) {
+		status, err = p.Confirmer.Confirm(txID)
+		return err
+	})
+	return status, err
+}
+
+// settle returns the status of [txID], which may have been issued by a
+// previous run. If it isn't decided, its signed [txBytes] are issued again and
+// its status is confirmed once more. Reissuing the same bytes spends the same
+// UTXOs, so it can't pay for the transaction's batch twice.
+func (p *Pipeline) settle(txID ids.ID, txBytes []byte) (choices.Status, error) {
+	status, err := p.confirm(txID)
+	if err != nil || status.Decided() || txBytes == nil {
+		return status, err
+	}
+
+	log.Printf("reissuing undecided transaction %s", txID)
+	if err := p.issueTx(txID, txBytes); err != nil {
+		return choices.Unknown, fmt.Errorf("couldn't reissue: %w", err)
+	}
+	return p.confirm(txID)
+}
+
+// record writes the status of batch [i] to the journal, if there is one
+func (p *Pipeline) record(i int, txID ids.ID, txBytes []byte, status choices.Status) error {
+	if p.Journal == nil {
+		return nil
+	}
+	return p.Journal.record(i, txID, txBytes, status)
+}
+
+// retry calls [f] until it succeeds or has been retried [p.Retries] times,
+// doubling the time waited between each attempt.
+func (p *Pipeline) retry(f func() error) error {
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= p.Retries {
+			return err
+		}
+
+		log.Printf("retrying in %s after error: %s", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// sendState is the progress of a call to Send that is shared by its builders
+type sendState struct {
+	lock sync.Mutex
+	// cond is signaled whenever a batch is returned by a builder
+	cond *sync.Cond
+
+	txOuts [][]*avax.TransferableOutput
+	// unsent are the indices of the batches that haven't been attempted or
+	// that a builder couldn't fund. They are taken from the end.
+	unsent []int
+	// numActive is the number of batches that builders have taken and not
+	// yet sent or returned
+	numActive int
+	// fundingErrs are the errors of the last builder that couldn't fund each
+	// returned batch
+	fundingErrs map[int]error
+	err         error
+
+	start   time.Time
+	numTxs  int
+	numSent int
+}
+
+// take returns the index of a batch to send, or false if there are none left
+// or a transaction has failed. While batches are in flight, take waits for
+// them rather than returning false, as they may be returned by builders that
+// can't fund them.
+func (s *sendState) take() (int, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for s.err == nil && len(s.unsent) == 0 && s.numActive > 0 {
+		s.cond.Wait()
+	}
+	if s.err != nil || len(s.unsent) == 0 {
+		return 0, false
+	}
+	i := s.unsent[len(s.unsent)-1]
+	s.unsent = s.unsent[:len(s.unsent)-1]
+	s.numActive++
+	return i, true
+}
+
+// requeue returns batch [i], which a builder couldn't fund because of [err],
+// to the other builders
+func (s *sendState) requeue(i int, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.fundingErrs == nil {
+		s.fundingErrs = make(map[int]error)
+	}
+	s.fundingErrs[i] = err
+	s.unsent = append(s.unsent, i)
+	s.done()
+}
+
+// fail records [err] unless a failure was already recorded
+func (s *sendState) fail(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+	s.done()
+}
+
+// sent records that a transaction sending [numOutputs] was accepted and
+// returns the total number of outputs sent along with the rate they have been
+// sent at.
+func (s *sendState) sent(numOutputs int) (int, float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.numTxs++
+	s.numSent += numOutputs
+	s.done()
+	return s.numSent, s.rate()
+}
+
+// done records that a batch taken by a builder is no longer in flight and
+// wakes the builders waiting for it. The lock must be held.
+func (s *sendState) done() {
+	s.numActive--
+	s.cond.Broadcast()
+}
+
+// rate returns the number of outputs sent per second. The lock must be held
+// or every builder must have returned.
+func (s *sendState) rate() float64 {
+	return float64(s.numSent) / time.Since(s.start).Seconds()
+}
@@ -0,0 +1,140 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+)
+
+// testBuilder builds transactions with unique IDs and no inputs
+type testBuilder struct {
+	lock   sync.Mutex
+	nextID byte
+	issued int
+}
+
+func (b *testBuilder) Build([]*avax.TransferableOutput) (*Tx, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.nextID++
+	return &Tx{
+		ID:    ids.ID{b.nextID},
+		Bytes: []byte{b.nextID},
+	}, nil
+}
+
+func (b *testBuilder) Issued(*Tx) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.issued++
+}
+
+func (b *testBuilder) numIssued() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.issued
+}
+
+// blockingChain accepts every transaction once [accept] is closed
+type blockingChain struct {
+	accept chan struct{}
+}
+
+func (*blockingChain) IssueTx(txBytes []byte) (ids.ID, error) {
+	return ids.ID{txBytes[0]}, nil
+}
+
+func (c *blockingChain) Confirm(ids.ID) (choices.Status, error) {
+	<-c.accept
+	return choices.Accepted, nil
+}
+
+func TestPipelineIssuesBeforeAcceptance(t *testing.T) {
+	const (
+		numTxs     = 5
+		maxPending = 3
+	)
+	b := &testBuilder{}
+	chain := &blockingChain{accept: make(chan struct{})}
+	p := &Pipeline{
+		Builders:   []Builder{b},
+		Issuer:     chain,
+		Confirmer:  chain,
+		MaxPending: maxPending,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- p.Send(newTestTxOuts(numTxs))
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for b.numIssued() < maxPending {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d transactions to be issued before any was accepted, got %d", maxPending, b.numIssued())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// Give the builder time to exceed its limit
+	time.Sleep(50 * time.Millisecond)
+	if numIssued := b.numIssued(); numIssued != maxPending {
+		t.Fatalf("expected at most %d pending transactions, got %d", maxPending, numIssued)
+	}
+
+	close(chain.accept)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if numIssued := b.numIssued(); numIssued != numTxs {
+		t.Fatalf("expected %d transactions to be issued, got %d", numTxs, numIssued)
+	}
+}
+
+// timeoutChain receives every transaction but reports that issuing it failed
+type timeoutChain struct {
+	issued   int
+	received map[ids.ID]bool
+}
+
+func (c *timeoutChain) IssueTx(txBytes []byte) (ids.ID, error) {
+	c.issued++
+	c.received[ids.ID{txBytes[0]}] = true
+	return ids.Empty, errors.New("timed out")
+}
+
+func (*timeoutChain) Confirm(ids.ID) (choices.Status, error) {
+	return choices.Accepted, nil
+}
+
+func (c *timeoutChain) Status(txID ids.ID) (choices.Status, error) {
+	if c.received[txID] {
+		return choices.Processing, nil
+	}
+	return choices.Unknown, nil
+}
+
+func TestIssueTxChecksStatusBeforeRetrying(t *testing.T) {
+	chain := &timeoutChain{received: make(map[ids.ID]bool)}
+	p := &Pipeline{
+		Issuer:    chain,
+		Confirmer: chain,
+		Retries:   defaultRetries,
+	}
+	if err := p.issueTx(ids.ID{1}, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if chain.issued != 1 {
+		t.Fatalf("expected a received transaction to be issued once, got %d", chain.issued)
+	}
+}
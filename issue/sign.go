@@ -0,0 +1,150 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/components/verify"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+var (
+	errCantSpend = errors.New("can't spend output")
+	errLocked    = errors.New("can't spend locked output")
+)
+
+// spend returns an input consuming [out] that is authorized by addresses in
+// [addrs] at [time], along with the addresses that must sign the input. A
+// stakeable locked output can only be spent once it has unlocked, after which
+// it is spent like the output it wraps.
+func spend(
+	out verify.Verifiable,
+	addrs ids.ShortSet,
+	time uint64,
+) (avax.TransferableIn, []ids.ShortID, error) {
+	if lockedOut, ok := out.(*platformvm.StakeableLockOut); ok {
+		if time < lockedOut.Locktime {
+			return nil, nil, fmt.Errorf("%w: stakeable locked until %d", errLocked, lockedOut.Locktime)
+		}
+		out = lockedOut.TransferableOut
+	}
+
+	transferOut, ok := out.(*secp256k1fx.TransferOutput)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w of type %T", errCantSpend, out)
+	}
+
+	owners := &transferOut.OutputOwners
+	if time < owners.Locktime {
+		return nil, nil, fmt.Errorf("%w: locked until %d", errLocked, owners.Locktime)
+	}
+
+	sigIndices := make([]uint32, 0, owners.Threshold)
+	signers := make([]ids.ShortID, 0, owners.Threshold)
+	for i := 0; i < len(owners.Addrs) && uint32(len(signers)) < owners.Threshold; i++ {
+		if addr := owners.Addrs[i]; addrs.Contains(addr) {
+			sigIndices = append(sigIndices, uint32(i))
+			signers = append(signers, addr)
+		}
+	}
+	if uint32(len(signers)) != owners.Threshold {
+		return nil, nil, fmt.Errorf("%w requiring %d signatures", errCantSpend, owners.Threshold)
+	}
+
+	return &secp256k1fx.TransferInput{
+		Amt: transferOut.Amt,
+		Input: secp256k1fx.Input{
+			SigIndices: sigIndices,
+		},
+	}, signers, nil
+}
+
+// signPlatformTx signs every input of [tx] with the corresponding [signers]
+// and initializes the signed transaction.
+func signPlatformTx(tx *platformvm.Tx, s signer.Signer, signers [][]ids.ShortID) error {
+	unsignedBytes, err := platformvm.Codec.Marshal(txs.CodecVersion, &tx.UnsignedTx)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal UnsignedTx: %w", err)
+	}
+
+	creds, err := signer.Credentials(s, unsignedBytes, signers)
+	if err != nil {
+		return err
+	}
+	for _, cred := range creds {
+		tx.Creds = append(tx.Creds, cred)
+	}
+
+	signedBytes, err := platformvm.Codec.Marshal(txs.CodecVersion, tx)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal Tx: %w", err)
+	}
+	tx.Initialize(unsignedBytes, signedBytes)
+	return nil
+}
+
+// signAVMTx signs every input of [tx] with the corresponding [signers] and
+// initializes the signed transaction.
+func signAVMTx(tx *avm.Tx, s signer.Signer, signers [][]ids.ShortID) error {
+	unsignedBytes, err := c.Marshal(txs.CodecVersion, &tx.UnsignedTx)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal UnsignedTx: %w", err)
+	}
+
+	creds, err := signer.Credentials(s, unsignedBytes, signers)
+	if err != nil {
+		return err
+	}
+	for _, cred := range creds {
+		tx.Creds = append(tx.Creds, &avm.FxCredential{Verifiable: cred})
+	}
+
+	signedBytes, err := c.Marshal(txs.CodecVersion, tx)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal Tx: %w", err)
+	}
+	tx.Initialize(unsignedBytes, signedBytes)
+	return nil
+}
+
+type innerSortInputsWithSigners struct {
+	ins     []*avax.TransferableInput
+	signers [][]ids.ShortID
+}
+
+func (s *innerSortInputsWithSigners) Less(i, j int) bool {
+	iID, iIndex := s.ins[i].InputSource()
+	jID, jIndex := s.ins[j].InputSource()
+
+	switch bytes.Compare(iID[:], jID[:]) {
+	case -1:
+		return true
+	case 0:
+		return iIndex < jIndex
+	default:
+		return false
+	}
+}
+func (s *innerSortInputsWithSigners) Len() int { return len(s.ins) }
+func (s *innerSortInputsWithSigners) Swap(i, j int) {
+	s.ins[j], s.ins[i] = s.ins[i], s.ins[j]
+	s.signers[j], s.signers[i] = s.signers[i], s.signers[j]
+}
+
+// sortInputsWithSigners sorts the inputs and their signers by the input's
+// UTXO ID
+func sortInputsWithSigners(ins []*avax.TransferableInput, signers [][]ids.ShortID) {
+	sort.Sort(&innerSortInputsWithSigners{ins: ins, signers: signers})
+}
@@ -0,0 +1,170 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/utils/math"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+const (
+	// maxTxSize is the largest transaction that the nodes accept
+	maxTxSize = 256 * units.KiB
+
+	// DefaultMaxInputs is the number of inputs that a transaction is assumed
+	// to be funded by when no limit is configured
+	DefaultMaxInputs = 256
+
+	// credentialOverhead is the size of a credential's type ID and number of
+	// signatures
+	credentialOverhead = 2 * wrappers.IntLen
+)
+
+var (
+	errTxTooLarge     = errors.New("transaction is too large")
+	errOutputTooLarge = errors.New("output is too large to fit in a transaction")
+	errNoOutputsRoom  = errors.New("inputs leave no room for outputs")
+)
+
+// SignedTxSize returns the size of [tx] once each of its inputs has been
+// signed by the corresponding [signers].
+func SignedTxSize(tx txs.UnsignedTx, signers [][]ids.ShortID) int {
+	// The unsigned bytes are followed by the number of credentials
+	size := len(tx.Bytes()) + wrappers.IntLen
+	for _, inputSigners := range signers {
+		size += credentialOverhead + len(inputSigners)*crypto.SECP256K1RSigLen
+	}
+	return size
+}
+
+// checkTxSize returns an error if [tx], once signed by [signers], is larger
+// than the nodes accept
+func checkTxSize(tx txs.UnsignedTx, signers [][]ids.ShortID) error {
+	if size := SignedTxSize(tx, signers); size > maxTxSize {
+		return fmt.Errorf("%w: %d bytes with %d inputs, but at most %d bytes are allowed",
+			errTxTooLarge,
+			size,
+			len(signers),
+			maxTxSize,
+		)
+	}
+	return nil
+}
+
+// OutputsSizeLimit returns the number of bytes of outputs that a transaction
+// of [kind] may have if it's funded by [numInputs] inputs, each requiring one
+// signature, and returns change of the fee asset owned by up to [changeAddrs]
+// addresses.
+func OutputsSizeLimit(kind TxKind, numInputs int, changeAddrs int) (int, error) {
+	ins := make([]*avax.TransferableInput, numInputs)
+	signers := make([][]ids.ShortID, numInputs)
+	for i := range ins {
+		ins[i] = &avax.TransferableInput{
+			UTXOID: avax.UTXOID{OutputIndex: uint32(i)},
+			In: &secp256k1fx.TransferInput{
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}
+		signers[i] = []ids.ShortID{{}}
+	}
+
+	tx, _, err := kind.Build(nil, nil, ins)
+	if err != nil {
+		return 0, err
+	}
+	limit := maxTxSize - SignedTxSize(tx, signers) - changeOutputSize(changeAddrs)
+	if limit <= 0 {
+		return 0, fmt.Errorf("%w: %d inputs", errNoOutputsRoom, numInputs)
+	}
+	return limit, nil
+}
+
+// changeOutputSize returns the size of a change output owned by [numAddrs]
+// addresses
+func changeOutputSize(numAddrs int) int {
+	if numAddrs < 1 {
+		numAddrs = 1
+	}
+	size, err := outputSize(&avax.TransferableOutput{
+		Out: &secp256k1fx.TransferOutput{
+			OutputOwners: secp256k1fx.OutputOwners{
+				Threshold: 1,
+				Addrs:     make([]ids.ShortID, numAddrs),
+			},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return size
+}
+
+// outputSize returns the number of bytes that [out] adds to a transaction
+func outputSize(out *avax.TransferableOutput) (int, error) {
+	outBytes, err := c.Marshal(txs.CodecVersion, out)
+	if err != nil {
+		return 0, err
+	}
+	// The output is marshalled as part of the transaction, so it doesn't have
+	// its own codec version
+	return len(outBytes) - wrappers.ShortLen, nil
+}
+
+// PackOutputs splits [outs], in order, into the batches sent by each
+// transaction. A batch has at most [maxOutputsPerTx] outputs, and the amount
+// of each of its assets fits in a uint64. Its outputs, along with change for
+// each of its assets owned by up to [changeAddrs] addresses, take at most
+// [maxOutputsSize] bytes, as returned by OutputsSizeLimit.
+func PackOutputs(outs []*avax.TransferableOutput, maxOutputsSize int, changeAddrs int) ([][]*avax.TransferableOutput, error) {
+	var (
+		changeSize  = changeOutputSize(changeAddrs)
+		txOuts      [][]*avax.TransferableOutput
+		currentOuts []*avax.TransferableOutput
+		currentSize int
+		amounts     = make(map[ids.ID]uint64)
+	)
+	for _, out := range outs {
+		size, err := outputSize(out)
+		if err != nil {
+			return nil, err
+		}
+		if size+changeSize > maxOutputsSize {
+			return nil, errOutputTooLarge
+		}
+
+		assetID := out.AssetID()
+		amount, hasAsset := amounts[assetID]
+		newAmount, err := math.Add64(amount, out.Out.Amount())
+		newSize := currentSize + size
+		if !hasAsset {
+			// Leave room for the change of a new asset
+			newSize += changeSize
+		}
+		if err != nil || len(currentOuts) >= maxOutputsPerTx || newSize > maxOutputsSize {
+			txOuts = append(txOuts, currentOuts)
+			currentOuts = nil
+			amounts = make(map[ids.ID]uint64)
+			newAmount = out.Out.Amount()
+			newSize = size + changeSize
+		}
+
+		amounts[assetID] = newAmount
+		currentOuts = append(currentOuts, out)
+		currentSize = newSize
+	}
+	if len(currentOuts) > 0 {
+		txOuts = append(txOuts, currentOuts)
+	}
+	return txOuts, nil
+}
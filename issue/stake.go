@@ -0,0 +1,398 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/math"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+var errBalanceOverflow = errors.New("balance overflows uint64")
+
+// StakeInputs returns the inputs, spending [utxos] authorized by [addrs], that
+// stake [amount] of [assetID] and burn [fee] of it.
+//
+// Stakeable locked UTXOs are staked first. Their staked and returned outputs
+// keep their owners and locktime, as the funds remain locked. Unlocked UTXOs
+// then pay the fee and stake the rest, and their staked and returned outputs
+// are owned by [changeAddr].
+func StakeInputs(
+	utxos map[ids.ID]*avax.UTXO,
+	addrs ids.ShortSet,
+	assetID ids.ID,
+	amount uint64,
+	fee uint64,
+	changeAddr ids.ShortID,
+) (
+	ins []*avax.TransferableInput,
+	returned []*avax.TransferableOutput,
+	staked []*avax.TransferableOutput,
+	signers [][]ids.ShortID,
+	err error,
+) {
+	utxoIDs := make([]ids.ID, 0, len(utxos))
+	for utxoID, utxo := range utxos {
+		if utxo.AssetID() == assetID {
+			utxoIDs = append(utxoIDs, utxoID)
+		}
+	}
+	ids.SortIDs(utxoIDs)
+
+	now := uint64(time.Now().Unix())
+	var amountStaked uint64
+	for _, utxoID := range utxoIDs {
+		if amountStaked >= amount {
+			break
+		}
+
+		utxo := utxos[utxoID]
+		lockedOut, ok := utxo.Out.(*platformvm.StakeableLockOut)
+		if !ok || lockedOut.Locktime <= now {
+			// unlocked UTXOs are only used once the locked ones run out
+			continue
+		}
+		innerOut, ok := lockedOut.TransferableOut.(*secp256k1fx.TransferOutput)
+		if !ok {
+			continue
+		}
+		input, inputSigners, err := spend(innerOut, addrs, now)
+		if err != nil {
+			// this utxo can't be spent with the current keys
+			continue
+		}
+
+		toStake := amount - amountStaked
+		if available := input.Amount(); available < toStake {
+			toStake = available
+		}
+		amountStaked += toStake
+
+		ins = append(ins, &avax.TransferableInput{
+			UTXOID: utxo.UTXOID,
+			Asset:  avax.Asset{ID: assetID},
+			In: &platformvm.StakeableLockIn{
+				Locktime:       lockedOut.Locktime,
+				TransferableIn: input,
+			},
+		})
+		signers = append(signers, inputSigners)
+		staked = append(staked, lockedOutput(assetID, lockedOut.Locktime, innerOut.OutputOwners, toStake))
+		if remaining := input.Amount() - toStake; remaining > 0 {
+			returned = append(returned, lockedOutput(assetID, lockedOut.Locktime, innerOut.OutputOwners, remaining))
+		}
+	}
+
+	// The rest is funded by unlocked UTXOs
+	unlockedUTXOs := make(map[ids.ID]*avax.UTXO, len(utxoIDs))
+	for _, utxoID := range utxoIDs {
+		utxo := utxos[utxoID]
+		if lockedOut, ok := utxo.Out.(*platformvm.StakeableLockOut); ok && lockedOut.Locktime > now {
+			continue
+		}
+		unlockedUTXOs[utxoID] = utxo
+	}
+	toStake := amount - amountStaked
+	cost, err := math.Add64(toStake, fee)
+	if err != nil {
+		return nil, nil, nil, nil, errSpendOverflow
+	}
+	if cost > 0 {
+		spent, unlockedIns, unlockedSigners, err := BuildInputs(unlockedUTXOs, addrs, map[ids.ID]uint64{assetID: cost}, LargestFirst{}, 0)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("couldn't fund unlocked stake of %d and fee of %d: %w", toStake, fee, err)
+		}
+		ins = append(ins, unlockedIns...)
+		signers = append(signers, unlockedSigners...)
+
+		owners := secp256k1fx.OutputOwners{
+			Threshold: 1,
+			Addrs:     []ids.ShortID{changeAddr},
+		}
+		if toStake > 0 {
+			staked = append(staked, &avax.TransferableOutput{
+				Asset: avax.Asset{ID: assetID},
+				Out: &secp256k1fx.TransferOutput{
+					Amt:          toStake,
+					OutputOwners: owners,
+				},
+			})
+		}
+		if remaining := spent[assetID] - cost; remaining > 0 {
+			returned = append(returned, &avax.TransferableOutput{
+				Asset: avax.Asset{ID: assetID},
+				Out: &secp256k1fx.TransferOutput{
+					Amt:          remaining,
+					OutputOwners: owners,
+				},
+			})
+		}
+	}
+
+	sortInputsWithSigners(ins, signers)
+	avax.SortTransferableOutputs(returned, platformvm.Codec)
+	avax.SortTransferableOutputs(staked, platformvm.Codec)
+	return ins, returned, staked, signers, nil
+}
+
+func lockedOutput(assetID ids.ID, locktime uint64, owners secp256k1fx.OutputOwners, amount uint64) *avax.TransferableOutput {
+	return &avax.TransferableOutput{
+		Asset: avax.Asset{ID: assetID},
+		Out: &platformvm.StakeableLockOut{
+			Locktime: locktime,
+			TransferableOut: &secp256k1fx.TransferOutput{
+				Amt:          amount,
+				OutputOwners: owners,
+			},
+		},
+	}
+}
+
+// BuildAddDelegatorTx builds, without signing, a transaction delegating
+// [weight] of [assetID] to [nodeID] from [start] until [end], funded as
+// described by StakeInputs. The addresses that must sign each input are
+// returned along with it.
+func BuildAddDelegatorTx(
+	networkID uint32,
+	chainID ids.ID,
+	nodeID ids.ShortID,
+	start uint64,
+	end uint64,
+	weight uint64,
+	rewardAddr ids.ShortID,
+	utxos map[ids.ID]*avax.UTXO,
+	addrs ids.ShortSet,
+	assetID ids.ID,
+	fee uint64,
+	changeAddr ids.ShortID,
+) (txs.UnsignedTx, [][]ids.ShortID, error) {
+	ins, returned, staked, signers, err := StakeInputs(utxos, addrs, assetID, weight, fee, changeAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := txs.NewPlatformTx(&platformvm.UnsignedAddDelegatorTx{
+		BaseTx: platformvm.BaseTx{BaseTx: avax.BaseTx{
+			NetworkID:    networkID,
+			BlockchainID: chainID,
+			Ins:          ins,
+			Outs:         returned,
+		}},
+		Validator: platformvm.Validator{
+			NodeID: nodeID,
+			Start:  start,
+			End:    end,
+			Wght:   weight,
+		},
+		Stake: staked,
+		RewardsOwner: &secp256k1fx.OutputOwners{
+			Threshold: 1,
+			Addrs:     []ids.ShortID{rewardAddr},
+		},
+	})
+	return tx, signers, err
+}
+
+// Delegation describes the stake delegated to a validator of the primary
+// network
+type Delegation struct {
+	NodeID ids.ShortID
+	// Start and End are the unix times at which the delegation starts and
+	// ends
+	Start uint64
+	End   uint64
+	// Weight is the amount of AVAX delegated
+	Weight uint64
+	// RewardAddr receives the reward of the delegation
+	RewardAddr ids.ShortID
+	// Fee is the amount of AVAX burned by the transaction
+	Fee uint64
+}
+
+// Delegate issues the transaction delegating [d], funded by the P-chain UTXOs
+// of [s], and waits for it to be accepted. Stakeable locked UTXOs are staked
+// before unlocked ones, and unlocked change is returned to the first address
+// of [s].
+func Delegate(
+	networkID uint32,
+	pClient *platformvm.Client,
+	s signer.Signer,
+	assetID ids.ID,
+	d Delegation,
+) (ids.ID, error) {
+	utxos, err := NewPChainUTXOSource(networkID, pClient, s.Addresses()).UTXOs()
+	if err != nil {
+		return ids.Empty, err
+	}
+	tx, signers, err := BuildAddDelegatorTx(
+		networkID,
+		constants.PlatformChainID,
+		d.NodeID,
+		d.Start,
+		d.End,
+		d.Weight,
+		d.RewardAddr,
+		utxos,
+		signer.AddressSet(s),
+		assetID,
+		d.Fee,
+		s.Addresses()[0],
+	)
+	if err != nil {
+		return ids.Empty, err
+	}
+	if err := checkTxSize(tx, signers); err != nil {
+		return ids.Empty, err
+	}
+	txBytes, err := signer.SignTx(tx, s, signers)
+	if err != nil {
+		return ids.Empty, err
+	}
+
+	txID, err := pClient.IssueTx(txBytes)
+	if err != nil {
+		return ids.Empty, fmt.Errorf("couldn't issue transaction: %w", err)
+	}
+	status, err := NewPChainConfirmer(pClient).Confirm(txID)
+	if err != nil {
+		return txID, fmt.Errorf("couldn't confirm transaction %s: %w", txID, err)
+	}
+	if status != choices.Accepted {
+		return txID, fmt.Errorf("%w: transaction %s has status %s", errNotAccepted, txID, status)
+	}
+	return txID, nil
+}
+
+// Balance is the amount of an asset owned by an address on the P-chain
+type Balance struct {
+	Unlocked uint64 `json:"unlocked"`
+	// LockedStakeable may be staked, but not transferred, until it unlocks
+	LockedStakeable uint64 `json:"lockedStakeable"`
+	// LockedNotStakeable can't be used until it unlocks
+	LockedNotStakeable uint64 `json:"lockedNotStakeable"`
+	// NextUnlock is the earliest unix time at which any of the locked balance
+	// unlocks, or 0 if nothing is locked
+	NextUnlock uint64 `json:"nextUnlock,omitempty"`
+}
+
+// Balances returns the balance of [assetID] owned by each of [addrs] at
+// [now]. A UTXO with multiple owners counts towards the balance of each of
+// them.
+func Balances(
+	utxos map[ids.ID]*avax.UTXO,
+	addrs []ids.ShortID,
+	assetID ids.ID,
+	now uint64,
+) (map[ids.ShortID]*Balance, error) {
+	balances := make(map[ids.ShortID]*Balance, len(addrs))
+	for _, addr := range addrs {
+		balances[addr] = &Balance{}
+	}
+
+	for _, utxo := range utxos {
+		out, stakeableLocktime, ok := balanceOutput(utxo, assetID)
+		if !ok {
+			continue
+		}
+		for _, addr := range out.Addrs {
+			balance, ok := balances[addr]
+			if !ok {
+				continue
+			}
+			if err := balance.add(out, stakeableLocktime, now); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return balances, nil
+}
+
+// TotalBalance returns the balance of [assetID] owned by any of [addrs] at
+// [now]. Unlike the sum of the Balances of [addrs], a UTXO with multiple
+// owners is only counted once.
+func TotalBalance(
+	utxos map[ids.ID]*avax.UTXO,
+	addrs []ids.ShortID,
+	assetID ids.ID,
+	now uint64,
+) (*Balance, error) {
+	owners := ids.ShortSet{}
+	owners.Add(addrs...)
+
+	total := &Balance{}
+	for _, utxo := range utxos {
+		out, stakeableLocktime, ok := balanceOutput(utxo, assetID)
+		if !ok {
+			continue
+		}
+		for _, addr := range out.Addrs {
+			if !owners.Contains(addr) {
+				continue
+			}
+			if err := total.add(out, stakeableLocktime, now); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return total, nil
+}
+
+// balanceOutput returns the output of [utxo], if it holds [assetID], along
+// with the time that it's stakeable locked until
+func balanceOutput(utxo *avax.UTXO, assetID ids.ID) (*secp256k1fx.TransferOutput, uint64, bool) {
+	if utxo.AssetID() != assetID {
+		return nil, 0, false
+	}
+
+	var stakeableLocktime uint64
+	out := utxo.Out
+	if lockedOut, ok := out.(*platformvm.StakeableLockOut); ok {
+		stakeableLocktime = lockedOut.Locktime
+		out = lockedOut.TransferableOut
+	}
+	transferOut, ok := out.(*secp256k1fx.TransferOutput)
+	return transferOut, stakeableLocktime, ok
+}
+
+// add adds [out], which is stakeable locked until [stakeableLocktime], to the
+// balance at [now]
+func (b *Balance) add(out *secp256k1fx.TransferOutput, stakeableLocktime uint64, now uint64) error {
+	var (
+		amount *uint64
+		err    error
+	)
+	switch {
+	case out.Locktime > now:
+		amount = &b.LockedNotStakeable
+		b.unlocksAt(out.Locktime)
+	case stakeableLocktime > now:
+		amount = &b.LockedStakeable
+		b.unlocksAt(stakeableLocktime)
+	default:
+		amount = &b.Unlocked
+	}
+	*amount, err = math.Add64(*amount, out.Amt)
+	if err != nil {
+		return errBalanceOverflow
+	}
+	return nil
+}
+
+func (b *Balance) unlocksAt(locktime uint64) {
+	if b.NextUnlock == 0 || locktime < b.NextUnlock {
+		b.NextUnlock = locktime
+	}
+}
@@ -0,0 +1,208 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+var (
+	testAddr    = ids.ShortID{1}
+	testAssetID = ids.ID{2}
+)
+
+// newTestUTXO returns a UTXO of [amount] owned by [testAddr]. If
+// [stakeableLocktime] is non-zero, the UTXO is stakeable locked until then.
+func newTestUTXO(txID byte, amount uint64, stakeableLocktime uint64) *avax.UTXO {
+	var out avax.TransferableOut = &secp256k1fx.TransferOutput{
+		Amt: amount,
+		OutputOwners: secp256k1fx.OutputOwners{
+			Threshold: 1,
+			Addrs:     []ids.ShortID{testAddr},
+		},
+	}
+	if stakeableLocktime != 0 {
+		out = &platformvm.StakeableLockOut{
+			Locktime:        stakeableLocktime,
+			TransferableOut: out,
+		}
+	}
+	return &avax.UTXO{
+		UTXOID: avax.UTXOID{TxID: ids.ID{txID}},
+		Asset:  avax.Asset{ID: testAssetID},
+		Out:    out,
+	}
+}
+
+// newTestUTXOs returns a locked UTXO of 100, a UTXO of 50 whose stakeable
+// lock has expired, and an unlocked UTXO of 70
+func newTestUTXOs() map[ids.ID]*avax.UTXO {
+	now := uint64(time.Now().Unix())
+	utxos := make(map[ids.ID]*avax.UTXO)
+	for _, utxo := range []*avax.UTXO{
+		newTestUTXO(1, 100, now+3600),
+		newTestUTXO(2, 50, now-1),
+		newTestUTXO(3, 70, 0),
+	} {
+		utxos[utxo.InputID()] = utxo
+	}
+	return utxos
+}
+
+func testAddrs() ids.ShortSet {
+	addrs := ids.ShortSet{}
+	addrs.Add(testAddr)
+	return addrs
+}
+
+func sumOutputs(outs []*avax.TransferableOutput) uint64 {
+	var sum uint64
+	for _, out := range outs {
+		sum += out.Out.Amount()
+	}
+	return sum
+}
+
+func TestStakeInputsStakesLockedFirst(t *testing.T) {
+	ins, returned, staked, signers, err := StakeInputs(newTestUTXOs(), testAddrs(), testAssetID, 130, 10, testAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signers) != len(ins) {
+		t.Fatalf("got %d signers for %d inputs", len(signers), len(ins))
+	}
+
+	var consumed, lockedStaked uint64
+	numLockedIns := 0
+	for _, in := range ins {
+		consumed += in.In.Amount()
+		if _, ok := in.In.(*platformvm.StakeableLockIn); ok {
+			numLockedIns++
+		}
+	}
+	for _, out := range staked {
+		if _, ok := out.Out.(*platformvm.StakeableLockOut); ok {
+			lockedStaked += out.Out.Amount()
+		}
+	}
+
+	switch {
+	case numLockedIns != 1:
+		t.Fatalf("expected the locked UTXO to be staked, got %d locked inputs", numLockedIns)
+	case lockedStaked != 100:
+		t.Fatalf("expected 100 to be staked while locked, got %d", lockedStaked)
+	case sumOutputs(staked) != 130:
+		t.Fatalf("expected 130 to be staked, got %d", sumOutputs(staked))
+	case consumed != sumOutputs(staked)+sumOutputs(returned)+10:
+		t.Fatalf("consumed %d but staked %d, returned %d, and burned 10", consumed, sumOutputs(staked), sumOutputs(returned))
+	}
+}
+
+func TestStakeInputsInsufficientFunds(t *testing.T) {
+	_, _, _, _, err := StakeInputs(newTestUTXOs(), testAddrs(), testAssetID, 220, 1, testAddr)
+	if !errors.Is(err, errInsufficientFunds) {
+		t.Fatalf("expected %v, got %v", errInsufficientFunds, err)
+	}
+}
+
+func TestBuildAddDelegatorTx(t *testing.T) {
+	now := uint64(time.Now().Unix())
+	tx, signers, err := BuildAddDelegatorTx(1, ids.Empty, ids.ShortID{3}, now, now+1000, 150, testAddr, newTestUTXOs(), testAddrs(), testAssetID, 0, testAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := txs.Parse(txs.P, tx.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	delegatorTx, ok := parsed.Tx().(*platformvm.UnsignedAddDelegatorTx)
+	if !ok {
+		t.Fatalf("expected an add delegator tx, got %T", parsed.Tx())
+	}
+	if len(signers) != len(delegatorTx.Ins) {
+		t.Fatalf("got %d signers for %d inputs", len(signers), len(delegatorTx.Ins))
+	}
+	if staked := sumOutputs(delegatorTx.Stake); staked != delegatorTx.Validator.Wght {
+		t.Fatalf("staked %d but delegated %d", staked, delegatorTx.Validator.Wght)
+	}
+}
+
+func TestBuildInputsSpendsUnlockedStakeableUTXOs(t *testing.T) {
+	spent, ins, _, err := BuildInputs(newTestUTXOs(), testAddrs(), map[ids.ID]uint64{testAssetID: 120}, LargestFirst{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spent[testAssetID] != 120 || len(ins) != 2 {
+		t.Fatalf("expected the unlocked UTXOs of 50 and 70 to be spent, got %d in %d inputs", spent[testAssetID], len(ins))
+	}
+	for _, in := range ins {
+		if _, ok := in.In.(*secp256k1fx.TransferInput); !ok {
+			t.Fatalf("expected unlocked inputs, got %T", in.In)
+		}
+	}
+}
+
+func TestBuildInputsReportsLockedFunds(t *testing.T) {
+	_, _, _, err := BuildInputs(newTestUTXOs(), testAddrs(), map[ids.ID]uint64{testAssetID: 200}, LargestFirst{}, 0)
+	if !errors.Is(err, errInsufficientFunds) {
+		t.Fatalf("expected %v, got %v", errInsufficientFunds, err)
+	}
+	if !strings.Contains(err.Error(), "100 more that is locked") {
+		t.Fatalf("expected the locked funds to be reported, got %q", err)
+	}
+}
+
+func TestTotalBalanceCountsSharedUTXOsOnce(t *testing.T) {
+	otherAddr := ids.ShortID{4}
+	now := uint64(time.Now().Unix())
+	utxos := newTestUTXOs()
+	shared := newTestUTXO(5, 30, now+60)
+	shared.Out.(*platformvm.StakeableLockOut).TransferableOut.(*secp256k1fx.TransferOutput).Addrs = []ids.ShortID{testAddr, otherAddr}
+	utxos[shared.InputID()] = shared
+
+	addrs := []ids.ShortID{testAddr, otherAddr}
+	balances, err := Balances(utxos, addrs, testAssetID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := balances[otherAddr].LockedStakeable; got != 30 {
+		t.Fatalf("expected the shared UTXO to count towards each owner, got %d", got)
+	}
+
+	total, err := TotalBalance(utxos, addrs, testAssetID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Balance{
+		Unlocked:        120,
+		LockedStakeable: 130,
+		NextUnlock:      now + 60,
+	}
+	if *total != expected {
+		t.Fatalf("expected total %+v, got %+v", expected, *total)
+	}
+}
+
+func TestTotalBalanceOverflow(t *testing.T) {
+	utxos := map[ids.ID]*avax.UTXO{}
+	for i := byte(1); i <= 2; i++ {
+		utxo := newTestUTXO(i, math.MaxUint64, 0)
+		utxos[utxo.InputID()] = utxo
+	}
+	if _, err := TotalBalance(utxos, []ids.ShortID{testAddr}, testAssetID, 0); err != errBalanceOverflow {
+		t.Fatalf("expected %v, got %v", errBalanceOverflow, err)
+	}
+}
@@ -7,9 +7,102 @@ import (
 	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/vms/avm"
 	"github.com/ava-labs/avalanchego/vms/platformvm"
 )
 
+const (
+	confirmAttempts = 100
+	confirmDelay    = 100 * time.Millisecond
+)
+
+// ChainClient issues, and tracks the status of, the transactions of one chain
+type ChainClient interface {
+	Issuer
+	Confirmer
+
+	// Status returns the current status of [txID] without waiting for it to
+	// be decided
+	Status(txID ids.ID) (choices.Status, error)
+}
+
+type xChainClient struct {
+	*avm.Client
+}
+
+// NewXChainClient returns a client that issues X-chain transactions and polls
+// their status.
+func NewXChainClient(xClient *avm.Client) ChainClient {
+	return &xChainClient{Client: xClient}
+}
+
+// NewXChainConfirmer returns a confirmer that polls the status of X-chain
+// transactions.
+func NewXChainConfirmer(xClient *avm.Client) Confirmer {
+	return NewXChainClient(xClient)
+}
+
+func (c *xChainClient) Confirm(txID ids.ID) (choices.Status, error) {
+	return c.ConfirmTx(txID, confirmAttempts, confirmDelay)
+}
+
+func (c *xChainClient) Status(txID ids.ID) (choices.Status, error) {
+	return c.GetTxStatus(txID)
+}
+
+type pChainClient struct {
+	*platformvm.Client
+}
+
+// NewPChainClient returns a client that issues P-chain transactions and polls
+// their status. Committed transactions are reported as accepted, aborted
+// transactions as rejected, and dropped transactions as unknown so that they
+// are issued again.
+func NewPChainClient(pClient *platformvm.Client) ChainClient {
+	return &pChainClient{Client: pClient}
+}
+
+// NewPChainConfirmer returns a confirmer that polls the status of P-chain
+// transactions. Committed transactions are reported as accepted, aborted
+// transactions as rejected, and dropped transactions as unknown.
+func NewPChainConfirmer(pClient *platformvm.Client) Confirmer {
+	return NewPChainClient(pClient)
+}
+
+func (c *pChainClient) Confirm(txID ids.ID) (choices.Status, error) {
+	status, err := ConfirmTx(c.Client, txID, confirmAttempts, confirmDelay)
+	if err != nil {
+		return choices.Unknown, err
+	}
+	return platformStatus(status), nil
+}
+
+func (c *pChainClient) Status(txID ids.ID) (choices.Status, error) {
+	resp, err := c.GetTxStatus(txID, false)
+	if err != nil {
+		return choices.Unknown, err
+	}
+	return platformStatus(resp.Status), nil
+}
+
+// platformStatus converts a P-chain transaction status into the status of a
+// decision.
+func platformStatus(status platformvm.Status) choices.Status {
+	switch status {
+	case platformvm.Committed:
+		return choices.Accepted
+	case platformvm.Aborted:
+		return choices.Rejected
+	case platformvm.Processing:
+		return choices.Processing
+	default:
+		// Dropped transactions were evicted from the mempool without being
+		// decided, so they can be issued again
+		return choices.Unknown
+	}
+}
+
 // ConfirmTx attempts to confirm [txID] by checking its status [attempts] times
 // with a [delay] in between each attempt. If the transaction has not been decided
 // by the final attempt, it returns the status of the last attempt.
@@ -24,7 +117,7 @@ func ConfirmTx(c *platformvm.Client, txID ids.ID, attempts int, delay time.Durat
 		status := resp.Status
 
 		switch status {
-		case platformvm.Committed, platformvm.Aborted:
+		case platformvm.Committed, platformvm.Aborted, platformvm.Dropped:
 			return status, nil
 		}
 
@@ -0,0 +1,133 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+// UnsignedOutputsOtherToP builds, without signing, the transactions that
+// import [txOuts] into the P-chain funded by the UTXOs of [addrs] exported
+// from [sourceChainID], chosen as configured by [config].
+func UnsignedOutputsOtherToP(
+	networkID uint32,
+	chainID ids.ID,
+	sourceChainID ids.ID,
+	pClient *platformvm.Client,
+	addrs []ids.ShortID,
+	txOuts [][]*avax.TransferableOutput,
+	feeAssetID ids.ID,
+	feeAmount uint64,
+	config SpendConfig,
+) ([]txs.UnsignedTx, []*avax.UTXO, error) {
+	source := NewPChainAtomicUTXOSource(networkID, sourceChainID, pClient, addrs)
+	kind := &ImportTxKind{
+		NetworkID:     networkID,
+		ChainID:       chainID,
+		SourceChainID: sourceChainID,
+	}
+	return buildUnsigned(source, kind, addrs, txOuts, feeAssetID, feeAmount, config)
+}
+
+// UnsignedOutputsXToOther builds, without signing, the transactions that
+// export [txOuts] from the X-chain to [destinationChainID] funded by the
+// X-chain UTXOs of [addrs], chosen as configured by [config].
+func UnsignedOutputsXToOther(
+	networkID uint32,
+	chainID ids.ID,
+	destinationChainID ids.ID,
+	xClient *avm.Client,
+	addrs []ids.ShortID,
+	txOuts [][]*avax.TransferableOutput,
+	feeAssetID ids.ID,
+	feeAmount uint64,
+	config SpendConfig,
+) ([]txs.UnsignedTx, []*avax.UTXO, error) {
+	source := NewXChainUTXOSource(networkID, xClient, addrs)
+	kind := &ExportTxKind{
+		NetworkID:          networkID,
+		ChainID:            chainID,
+		DestinationChainID: destinationChainID,
+	}
+	return buildUnsigned(source, kind, addrs, txOuts, feeAssetID, feeAmount, config)
+}
+
+// UnsignedOutputsXToX builds, without signing, the transactions that send
+// [txOuts] on the X-chain funded by the X-chain UTXOs of [addrs], chosen as
+// configured by [config].
+func UnsignedOutputsXToX(
+	networkID uint32,
+	chainID ids.ID,
+	xClient *avm.Client,
+	addrs []ids.ShortID,
+	txOuts [][]*avax.TransferableOutput,
+	feeAssetID ids.ID,
+	feeAmount uint64,
+	config SpendConfig,
+) ([]txs.UnsignedTx, []*avax.UTXO, error) {
+	source := NewXChainUTXOSource(networkID, xClient, addrs)
+	kind := &BaseTxKind{
+		NetworkID: networkID,
+		ChainID:   chainID,
+	}
+	return buildUnsigned(source, kind, addrs, txOuts, feeAssetID, feeAmount, config)
+}
+
+// buildUnsigned builds one unsigned transaction for each of [txOuts], funded
+// by the UTXOs of [addrs] fetched from [source], so that they can be signed
+// offline by signer.Sign. The UTXOs consumed by the transactions, which the
+// signer requires, are returned along with them.
+//
+// The ID of a transaction, and so the IDs of its change outputs, isn't known
+// until it is signed. So every transaction spends distinct UTXOs that already
+// exist, rather than the change of the transactions before it.
+func buildUnsigned(
+	source UTXOSource,
+	kind TxKind,
+	addrs []ids.ShortID,
+	txOuts [][]*avax.TransferableOutput,
+	feeAssetID ids.ID,
+	feeAmount uint64,
+	config SpendConfig,
+) ([]txs.UnsignedTx, []*avax.UTXO, error) {
+	watchOnly := signer.NewWatchOnly(addrs)
+	b := &SpendBuilder{
+		Source:     NewUTXOSet(source, signer.AddressSet(watchOnly)),
+		Kind:       kind,
+		Signer:     watchOnly,
+		FeeAssetID: feeAssetID,
+		Fee:        feeAmount,
+		Selector:   config.Selector,
+		MaxInputs:  config.MaxInputs,
+		Change:     config.Change,
+	}
+
+	var (
+		unsignedTxs = make([]txs.UnsignedTx, len(txOuts))
+		consumed    []*avax.UTXO
+	)
+	for i, outs := range txOuts {
+		tx, txConsumed, err := b.BuildUnsigned(outs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("couldn't build transaction %d: %w", i, err)
+		}
+		unsignedTxs[i] = tx
+		consumed = append(consumed, txConsumed...)
+
+		spent := &Tx{Inputs: make([]ids.ID, len(txConsumed))}
+		for j, utxo := range txConsumed {
+			spent.Inputs[j] = utxo.InputID()
+		}
+		b.Issued(spent)
+	}
+	return unsignedTxs, consumed, nil
+}
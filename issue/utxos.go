@@ -11,22 +11,95 @@ import (
 	"github.com/ava-labs/avalanchego/vms/avm"
 	"github.com/ava-labs/avalanchego/vms/components/avax"
 	"github.com/ava-labs/avalanchego/vms/platformvm"
-	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
 )
 
 const (
 	utxoPageSize = 1024
 )
 
+type xChainUTXOSource struct {
+	networkID uint32
+	client    *avm.Client
+	addrs     []ids.ShortID
+}
+
+// NewXChainUTXOSource returns a source that fetches the X-chain UTXOs owned by
+// [addrs] every time it is queried.
+func NewXChainUTXOSource(networkID uint32, xClient *avm.Client, addrs []ids.ShortID) UTXOSource {
+	return &xChainUTXOSource{
+		networkID: networkID,
+		client:    xClient,
+		addrs:     addrs,
+	}
+}
+
+func (s *xChainUTXOSource) UTXOs() (map[ids.ID]*avax.UTXO, error) {
+	return GetXChainUTXOs(s.networkID, s.client, s.addrs)
+}
+
+func (*xChainUTXOSource) Issued(*Tx) {}
+
+type pChainAtomicUTXOSource struct {
+	networkID   uint32
+	sourceChain ids.ID
+	client      *platformvm.Client
+	addrs       []ids.ShortID
+}
+
+// NewPChainAtomicUTXOSource returns a source that fetches the UTXOs owned by
+// [addrs] that were exported from [sourceChain] to the P-chain every time it
+// is queried.
+func NewPChainAtomicUTXOSource(
+	networkID uint32,
+	sourceChain ids.ID,
+	pClient *platformvm.Client,
+	addrs []ids.ShortID,
+) UTXOSource {
+	return &pChainAtomicUTXOSource{
+		networkID:   networkID,
+		sourceChain: sourceChain,
+		client:      pClient,
+		addrs:       addrs,
+	}
+}
+
+func (s *pChainAtomicUTXOSource) UTXOs() (map[ids.ID]*avax.UTXO, error) {
+	return GetPChainAtomicUTXOs(s.networkID, s.sourceChain, s.client, s.addrs)
+}
+
+func (*pChainAtomicUTXOSource) Issued(*Tx) {}
+
+type pChainUTXOSource struct {
+	networkID uint32
+	client    *platformvm.Client
+	addrs     []ids.ShortID
+}
+
+// NewPChainUTXOSource returns a source that fetches the P-chain UTXOs owned by
+// [addrs], including stakeable locked UTXOs, every time it is queried.
+func NewPChainUTXOSource(networkID uint32, pClient *platformvm.Client, addrs []ids.ShortID) UTXOSource {
+	return &pChainUTXOSource{
+		networkID: networkID,
+		client:    pClient,
+		addrs:     addrs,
+	}
+}
+
+func (s *pChainUTXOSource) UTXOs() (map[ids.ID]*avax.UTXO, error) {
+	return GetPChainUTXOs(s.networkID, s.client, s.addrs)
+}
+
+func (*pChainUTXOSource) Issued(*Tx) {}
+
 func GetXChainUTXOs(
 	networkID uint32,
 	xClient *avm.Client,
-	keychain *secp256k1fx.Keychain,
+	addrs []ids.ShortID,
 ) (map[ids.ID]*avax.UTXO, error) {
 	hrp := constants.GetHRP(networkID)
 
 	ownedAddresses := []string(nil)
-	for ownedAddr := range keychain.Addrs {
+	for _, ownedAddr := range addrs {
 		ownedAddress, err := formatting.FormatAddress("X", hrp, ownedAddr[:])
 		if err != nil {
 			return nil, err
@@ -62,12 +135,12 @@ func GetPChainAtomicUTXOs(
 	networkID uint32,
 	sourceChain ids.ID,
 	pClient *platformvm.Client,
-	keychain *secp256k1fx.Keychain,
+	addrs []ids.ShortID,
 ) (map[ids.ID]*avax.UTXO, error) {
 	hrp := constants.GetHRP(networkID)
 
 	ownedAddresses := []string(nil)
-	for ownedAddr := range keychain.Addrs {
+	for _, ownedAddr := range addrs {
 		ownedAddress, err := formatting.FormatAddress("P", hrp, ownedAddr[:])
 		if err != nil {
 			return nil, err
@@ -98,3 +171,46 @@ func GetPChainAtomicUTXOs(
 		}
 	}
 }
+
+// GetPChainUTXOs returns the P-chain UTXOs owned by [addrs]. Unlike atomic
+// UTXOs, these may be stakeable locked, so they're parsed with the P-chain's
+// codec.
+func GetPChainUTXOs(
+	networkID uint32,
+	pClient *platformvm.Client,
+	addrs []ids.ShortID,
+) (map[ids.ID]*avax.UTXO, error) {
+	hrp := constants.GetHRP(networkID)
+
+	ownedAddresses := []string(nil)
+	for _, ownedAddr := range addrs {
+		ownedAddress, err := formatting.FormatAddress("P", hrp, ownedAddr[:])
+		if err != nil {
+			return nil, err
+		}
+		ownedAddresses = append(ownedAddresses, ownedAddress)
+	}
+
+	utxos := make(map[ids.ID]*avax.UTXO)
+	index := api.Index{}
+	for {
+		rawUTXOs, newIndex, err := pClient.GetUTXOs(ownedAddresses, utxoPageSize, index.Address, index.UTXO)
+		if err != nil {
+			return nil, err
+		}
+		index = newIndex
+
+		for _, rawUTXO := range rawUTXOs {
+			utxo := avax.UTXO{}
+			_, err := platformvm.Codec.Unmarshal(rawUTXO, &utxo)
+			if err != nil {
+				return nil, err
+			}
+			utxos[utxo.InputID()] = &utxo
+		}
+
+		if len(rawUTXOs) != utxoPageSize {
+			return utxos, nil
+		}
+	}
+}
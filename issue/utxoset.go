@@ -0,0 +1,149 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package issue
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+)
+
+var _ UTXOSource = &UTXOSet{}
+
+// UTXOSet tracks the UTXOs owned by a set of addresses locally. The UTXOs are
+// fetched from the underlying source once. Afterwards, the inputs of every
+// issued transaction are removed and the outputs it sends back to the
+// addresses are added, so that the next transaction can spend them before
+// they are accepted.
+type UTXOSet struct {
+	source UTXOSource
+	addrs  ids.ShortSet
+	utxos  map[ids.ID]*avax.UTXO
+}
+
+// NewUTXOSet returns a set that is populated from [source] on first use and
+// tracks the UTXOs owned by [addrs].
+func NewUTXOSet(source UTXOSource, addrs ids.ShortSet) *UTXOSet {
+	return &UTXOSet{
+		source: source,
+		addrs:  addrs,
+	}
+}
+
+func (s *UTXOSet) UTXOs() (map[ids.ID]*avax.UTXO, error) {
+	if s.utxos != nil {
+		return s.utxos, nil
+	}
+
+	utxos, err := s.source.UTXOs()
+	if err != nil {
+		return nil, err
+	}
+	s.utxos = make(map[ids.ID]*avax.UTXO, len(utxos))
+	for utxoID, utxo := range utxos {
+		s.utxos[utxoID] = utxo
+	}
+	return s.utxos, nil
+}
+
+func (s *UTXOSet) Issued(tx *Tx) {
+	if s.utxos == nil {
+		return
+	}
+	for _, utxoID := range tx.Inputs {
+		delete(s.utxos, utxoID)
+	}
+	for _, utxo := range tx.UTXOs {
+		if s.owns(utxo) {
+			s.utxos[utxo.InputID()] = utxo
+		}
+	}
+	s.source.Issued(tx)
+}
+
+// Split divides the UTXOs into [n] disjoint sets, spreading the value of each
+// asset as evenly as possible between them. Transactions built from different
+// sets never spend the same UTXO, so they can be in flight at the same time.
+// The returned sets must be used in place of [s].
+func (s *UTXOSet) Split(n int) ([]*UTXOSet, error) {
+	if n < 1 {
+		n = 1
+	}
+	utxos, err := s.UTXOs()
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]*avax.UTXO, 0, len(utxos))
+	for _, utxo := range utxos {
+		sorted = append(sorted, utxo)
+	}
+	// Assigning the largest UTXOs first keeps the sets balanced
+	sort.Slice(sorted, func(i, j int) bool {
+		assetI, assetJ := sorted[i].AssetID(), sorted[j].AssetID()
+		if assetI != assetJ {
+			return bytes.Compare(assetI[:], assetJ[:]) < 0
+		}
+		amountI, amountJ := utxoAmount(sorted[i]), utxoAmount(sorted[j])
+		if amountI != amountJ {
+			return amountI > amountJ
+		}
+		idI, idJ := sorted[i].InputID(), sorted[j].InputID()
+		return bytes.Compare(idI[:], idJ[:]) < 0
+	})
+
+	sets := make([]*UTXOSet, n)
+	totals := make([]map[ids.ID]uint64, n)
+	for i := range sets {
+		sets[i] = &UTXOSet{
+			source: s.source,
+			addrs:  s.addrs,
+			utxos:  make(map[ids.ID]*avax.UTXO),
+		}
+		totals[i] = make(map[ids.ID]uint64)
+	}
+	for _, utxo := range sorted {
+		assetID := utxo.AssetID()
+		smallest := 0
+		for i := 1; i < n; i++ {
+			if totals[i][assetID] < totals[smallest][assetID] {
+				smallest = i
+			}
+		}
+
+		sets[smallest].utxos[utxo.InputID()] = utxo
+		totals[smallest][assetID] = addSaturating(totals[smallest][assetID], utxoAmount(utxo))
+	}
+	return sets, nil
+}
+
+// owns returns true if any of the addresses may be able to spend [utxo]
+func (s *UTXOSet) owns(utxo *avax.UTXO) bool {
+	utxoOut := utxo.Out
+	if lockedOut, ok := utxoOut.(*platformvm.StakeableLockOut); ok {
+		utxoOut = lockedOut.TransferableOut
+	}
+	out, ok := utxoOut.(*secp256k1fx.TransferOutput)
+	if !ok {
+		return false
+	}
+	for _, addr := range out.Addrs {
+		if s.addrs.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func utxoAmount(utxo *avax.UTXO) uint64 {
+	out, ok := utxo.Out.(avax.Amounter)
+	if !ok {
+		return 0
+	}
+	return out.Amount()
+}
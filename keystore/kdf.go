@@ -0,0 +1,132 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	keyLen  = 32
+	saltLen = 32
+
+	// scrypt parameters recommended for interactive use of file encryption
+	scryptN = 1 << 18
+	scryptR = 8
+	scryptP = 1
+
+	// argon2id parameters recommended by RFC 9106 for memory constrained
+	// environments
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+
+	// bounds enforced on the parameters of a keystore file so that a
+	// malformed file can't exhaust the machine's memory
+	maxScryptN      = 1 << 20
+	maxScryptR      = 32
+	maxScryptP      = 16
+	maxArgon2Memory = 4 * 1024 * 1024
+	maxArgon2Time   = 64
+)
+
+var errInvalidKDFParams = errors.New("invalid key derivation parameters")
+
+// KDF is the function used to derive the encryption key from a passphrase
+type KDF uint8
+
+const (
+	Scrypt KDF = iota
+	Argon2id
+)
+
+var kdfNames = []string{
+	Scrypt:   "scrypt",
+	Argon2id: "argon2id",
+}
+
+// ParseKDF returns the KDF named [name]
+func ParseKDF(name string) (KDF, error) {
+	for kdf, kdfName := range kdfNames {
+		if strings.EqualFold(name, kdfName) {
+			return KDF(kdf), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key derivation function %q", name)
+}
+
+func (k KDF) String() string {
+	if int(k) < len(kdfNames) {
+		return kdfNames[k]
+	}
+	return fmt.Sprintf("KDF(%d)", uint8(k))
+}
+
+func (k KDF) MarshalText() ([]byte, error) {
+	if int(k) >= len(kdfNames) {
+		return nil, fmt.Errorf("unknown key derivation function %d", uint8(k))
+	}
+	return []byte(k.String()), nil
+}
+
+func (k *KDF) UnmarshalText(text []byte) error {
+	kdf, err := ParseKDF(string(text))
+	if err != nil {
+		return err
+	}
+	*k = kdf
+	return nil
+}
+
+// kdfParams are the tuning parameters of the KDF. Only the parameters of the
+// file's KDF are populated.
+type kdfParams struct {
+	// scrypt
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"p,omitempty"`
+
+	// argon2id
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+func defaultParams(kdf KDF) (kdfParams, error) {
+	switch kdf {
+	case Scrypt:
+		return kdfParams{N: scryptN, R: scryptR, P: scryptP}, nil
+	case Argon2id:
+		return kdfParams{Time: argon2Time, Memory: argon2Memory, Threads: argon2Threads}, nil
+	default:
+		return kdfParams{}, fmt.Errorf("unknown key derivation function %d", uint8(kdf))
+	}
+}
+
+// deriveKey returns the encryption key derived from [passphrase] and [salt].
+func deriveKey(kdf KDF, params kdfParams, passphrase, salt []byte) ([]byte, error) {
+	switch kdf {
+	case Scrypt:
+		if params.N <= 1 || params.N > maxScryptN ||
+			params.R <= 0 || params.R > maxScryptR ||
+			params.P <= 0 || params.P > maxScryptP {
+			return nil, errInvalidKDFParams
+		}
+		return scrypt.Key(passphrase, salt, params.N, params.R, params.P, keyLen)
+	case Argon2id:
+		if params.Time == 0 || params.Time > maxArgon2Time ||
+			params.Memory == 0 || params.Memory > maxArgon2Memory ||
+			params.Threads == 0 {
+			return nil, errInvalidKDFParams
+		}
+		return argon2.IDKey(passphrase, salt, params.Time, params.Memory, params.Threads, keyLen), nil
+	default:
+		return nil, fmt.Errorf("unknown key derivation function %d", uint8(kdf))
+	}
+}
@@ -0,0 +1,217 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+)
+
+const version = 1
+
+var (
+	errUnsupportedVersion = errors.New("unsupported keystore version")
+	errNoKeys             = errors.New("keystore must contain at least one key")
+	errWrongPassphrase    = errors.New("wrong passphrase or corrupted keystore")
+	errMismatchedKeys     = errors.New("decrypted keys don't match the keystore's addresses")
+)
+
+// file is the JSON encoding of a keystore. The addresses are stored in the
+// clear so that the keystore can be listed without its passphrase. They are
+// checked against the decrypted keys when the keystore is loaded.
+type file struct {
+	Version    int           `json:"version"`
+	Addresses  []ids.ShortID `json:"addresses"`
+	KDF        KDF           `json:"kdf"`
+	Params     kdfParams     `json:"params"`
+	Salt       []byte        `json:"salt"`
+	Nonce      []byte        `json:"nonce"`
+	Ciphertext []byte        `json:"ciphertext"`
+}
+
+// Keys are the decrypted contents of a keystore
+type Keys struct {
+	Keychain *secp256k1fx.Keychain
+
+	// plaintext backs the serialized form of every key in the keychain
+	plaintext []byte
+}
+
+// Zero overwrites the decrypted key material. The keychain must not be used
+// afterwards.
+//
+// The keys' serialized bytes are cleared, but the scalars parsed from them by
+// the crypto library are not reachable and are left to the garbage collector.
+func (k *Keys) Zero() {
+	zero(k.plaintext)
+	k.Keychain = nil
+}
+
+// Create encrypts [keys] with [passphrase] and writes them to [path]. The
+// file is only readable by its owner.
+func Create(path string, keys []*crypto.PrivateKeySECP256K1R, passphrase []byte, kdf KDF) error {
+	if len(keys) == 0 {
+		return errNoKeys
+	}
+
+	params, err := defaultParams(kdf)
+	if err != nil {
+		return err
+	}
+
+	f := file{
+		Version:   version,
+		Addresses: make([]ids.ShortID, len(keys)),
+		KDF:       kdf,
+		Params:    params,
+		Salt:      make([]byte, saltLen),
+	}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+
+	plaintext := make([]byte, 0, len(keys)*crypto.SECP256K1RSKLen)
+	defer func() { zero(plaintext) }()
+	for i, key := range keys {
+		f.Addresses[i] = key.PublicKey().Address()
+		plaintext = append(plaintext, key.Bytes()...)
+	}
+
+	aead, err := newAEAD(kdf, params, passphrase, f.Salt)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Ciphertext = aead.Seal(nil, f.Nonce, plaintext, nil)
+
+	fileBytes, err := json.MarshalIndent(f, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	out, err := txio.CreatePrivateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	if _, err := out.Write(append(fileBytes, '\n')); err != nil {
+		return err
+	}
+	return out.Commit()
+}
+
+// Addresses returns the addresses of the keys in the keystore at [path]
+// without decrypting it.
+func Addresses(path string) ([]ids.ShortID, error) {
+	f, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Addresses, nil
+}
+
+// Load decrypts the keystore at [path] with [passphrase]. The caller should
+// Zero the returned keys once they are no longer needed.
+func Load(path string, passphrase []byte) (*Keys, error) {
+	f, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(f.KDF, f.Params, passphrase, f.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce length %d", errWrongPassphrase, len(f.Nonce))
+	}
+
+	plaintext, err := aead.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+
+	keys := &Keys{
+		Keychain:  secp256k1fx.NewKeychain(),
+		plaintext: plaintext,
+	}
+	if len(plaintext) != len(f.Addresses)*crypto.SECP256K1RSKLen {
+		keys.Zero()
+		return nil, errMismatchedKeys
+	}
+
+	secp := crypto.FactorySECP256K1R{}
+	for i, addr := range f.Addresses {
+		// The key keeps a reference to the slice of [plaintext] it was parsed
+		// from, so zeroing [plaintext] clears it.
+		start := i * crypto.SECP256K1RSKLen
+		skIntf, err := secp.ToPrivateKey(plaintext[start : start+crypto.SECP256K1RSKLen : start+crypto.SECP256K1RSKLen])
+		if err != nil {
+			keys.Zero()
+			return nil, err
+		}
+		sk := skIntf.(*crypto.PrivateKeySECP256K1R)
+		if sk.PublicKey().Address() != addr {
+			keys.Zero()
+			return nil, errMismatchedKeys
+		}
+		keys.Keychain.Add(sk)
+	}
+	return keys, nil
+}
+
+func readFile(path string) (*file, error) {
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &file{}
+	if err := json.Unmarshal(fileBytes, f); err != nil {
+		return nil, fmt.Errorf("couldn't parse keystore %q: %w", path, err)
+	}
+	if f.Version != version {
+		return nil, fmt.Errorf("%w: %d", errUnsupportedVersion, f.Version)
+	}
+	if len(f.Addresses) == 0 {
+		return nil, errNoKeys
+	}
+	return f, nil
+}
+
+// newAEAD returns the AES-256-GCM cipher keyed by [passphrase].
+func newAEAD(kdf KDF, params kdfParams, passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(kdf, params, passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
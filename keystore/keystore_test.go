@@ -0,0 +1,175 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+)
+
+var testPassphrase = []byte("passphrase")
+
+// newTestKeystore returns the file of a keystore of [keys] encrypted with
+// [testPassphrase] using [kdf]
+func newTestKeystore(t *testing.T, kdf KDF, keys []*crypto.PrivateKeySECP256K1R) *file {
+	path := filepath.Join(t.TempDir(), "keystore")
+	if err := Create(path, keys, testPassphrase, kdf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := readFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestLoad(t *testing.T) {
+	secp := crypto.FactorySECP256K1R{}
+	keys := make([]*crypto.PrivateKeySECP256K1R, 2)
+	for i := range keys {
+		skIntf, err := secp.NewPrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = skIntf.(*crypto.PrivateKeySECP256K1R)
+	}
+	keystores := map[KDF]*file{
+		Scrypt:   newTestKeystore(t, Scrypt, keys),
+		Argon2id: newTestKeystore(t, Argon2id, keys),
+	}
+
+	tests := []struct {
+		name        string
+		kdf         KDF
+		passphrase  string
+		tamper      func(f *file)
+		expectedErr error
+	}{
+		{
+			name:       "scrypt round trip",
+			kdf:        Scrypt,
+			passphrase: string(testPassphrase),
+		},
+		{
+			name:       "argon2id round trip",
+			kdf:        Argon2id,
+			passphrase: string(testPassphrase),
+		},
+		{
+			name:        "wrong passphrase",
+			kdf:         Scrypt,
+			passphrase:  "wrong",
+			expectedErr: errWrongPassphrase,
+		},
+		{
+			name:       "weakened scrypt parameters",
+			kdf:        Scrypt,
+			passphrase: string(testPassphrase),
+			tamper: func(f *file) {
+				f.Params.N /= 2
+			},
+			expectedErr: errWrongPassphrase,
+		},
+		{
+			name:       "weakened argon2id parameters",
+			kdf:        Argon2id,
+			passphrase: string(testPassphrase),
+			tamper: func(f *file) {
+				f.Params.Time = 1
+			},
+			expectedErr: errWrongPassphrase,
+		},
+		{
+			name:       "unbounded parameters",
+			kdf:        Scrypt,
+			passphrase: string(testPassphrase),
+			tamper: func(f *file) {
+				f.Params.N = 2 * maxScryptN
+			},
+			expectedErr: errInvalidKDFParams,
+		},
+		{
+			name:       "swapped key derivation function",
+			kdf:        Scrypt,
+			passphrase: string(testPassphrase),
+			tamper: func(f *file) {
+				f.KDF = Argon2id
+			},
+			expectedErr: errInvalidKDFParams,
+		},
+		{
+			name:       "tampered salt",
+			kdf:        Scrypt,
+			passphrase: string(testPassphrase),
+			tamper: func(f *file) {
+				f.Salt[0] ^= 1
+			},
+			expectedErr: errWrongPassphrase,
+		},
+		{
+			name:       "tampered ciphertext",
+			kdf:        Scrypt,
+			passphrase: string(testPassphrase),
+			tamper: func(f *file) {
+				f.Ciphertext[0] ^= 1
+			},
+			expectedErr: errWrongPassphrase,
+		},
+		{
+			name:       "tampered address",
+			kdf:        Scrypt,
+			passphrase: string(testPassphrase),
+			tamper: func(f *file) {
+				f.Addresses[1] = ids.ShortID{1}
+			},
+			expectedErr: errMismatchedKeys,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := *keystores[test.kdf]
+			f.Addresses = append([]ids.ShortID(nil), f.Addresses...)
+			f.Salt = append([]byte(nil), f.Salt...)
+			f.Ciphertext = append([]byte(nil), f.Ciphertext...)
+			if test.tamper != nil {
+				test.tamper(&f)
+			}
+			fileBytes, err := json.Marshal(&f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "keystore")
+			if err := os.WriteFile(path, fileBytes, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := Load(path, []byte(test.passphrase))
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected %v, got %v", test.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			defer loaded.Zero()
+
+			for _, key := range keys {
+				addr := key.PublicKey().Address()
+				loadedKey, ok := loaded.Keychain.Get(addr)
+				if !ok {
+					t.Fatalf("expected a key for %s", addr)
+				}
+				if !bytes.Equal(key.Bytes(), loadedKey.Bytes()) {
+					t.Fatalf("expected the key of %s to round trip", addr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/StephenButtolph/avalanche-tooling/audit"
+)
+
+func runAuditVerify(args []string) error {
+	fs := newFlagSet("audit-verify", "[-head <hash>] <audit log>")
+	expectedHeadStr := fs.String("head", "", "hash that the last record of the log must have, as printed by a previous verification")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	expectedHead := ids.Empty
+	if *expectedHeadStr != "" {
+		var err error
+		expectedHead, err = ids.FromString(*expectedHeadStr)
+		if err != nil {
+			return usageErrorf(fs, "invalid -head: %s", err)
+		}
+	}
+
+	numEntries, head, err := audit.Verify(fs.Arg(0), expectedHead)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d entries, head %s\n", numEntries, head)
+	return nil
+}
@@ -0,0 +1,76 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+
+	"github.com/StephenButtolph/avalanche-tooling/issue"
+)
+
+func runPBalances(args []string) error {
+	fs := newFlagSet("p-balances", "[-uri <node uri>] <addresses file>")
+	node := addNodeFlags(fs)
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	addrs, err := readAddresses(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	pClient := node.platformClient()
+	networkID, err := node.infoClient().GetNetworkID()
+	if err != nil {
+		return err
+	}
+	assetID, err := pClient.GetStakingAssetID(constants.PrimaryNetworkID)
+	if err != nil {
+		return err
+	}
+	utxos, err := issue.GetPChainUTXOs(networkID, pClient, addrs)
+	if err != nil {
+		return err
+	}
+
+	var (
+		hrp = constants.GetHRP(networkID)
+		now = uint64(time.Now().Unix())
+	)
+	balances, err := issue.Balances(utxos, addrs, assetID, now)
+	if err != nil {
+		return err
+	}
+	total, err := issue.TotalBalance(utxos, addrs, assetID, now)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		addrStr, err := formatting.FormatAddress("P", hrp, addr[:])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", addrStr, formatBalance(balances[addr]))
+	}
+	fmt.Printf("total: %s\n", formatBalance(total))
+	return nil
+}
+
+// formatBalance describes [b] in nAVAX
+func formatBalance(b *issue.Balance) string {
+	s := fmt.Sprintf("%d nAVAX unlocked, %d nAVAX locked stakeable, %d nAVAX locked not stakeable",
+		b.Unlocked,
+		b.LockedStakeable,
+		b.LockedNotStakeable,
+	)
+	if b.NextUnlock != 0 {
+		s += fmt.Sprintf(", next unlock at %s", time.Unix(int64(b.NextUnlock), 0).UTC().Format(time.RFC3339))
+	}
+	return s
+}
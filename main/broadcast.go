@@ -0,0 +1,38 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"os"
+
+	"github.com/ava-labs/avalanchego/vms/avm"
+
+	"github.com/StephenButtolph/avalanche-tooling/issue"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+func runBroadcast(args []string) error {
+	fs := newFlagSet("broadcast", "[-uri <node uri>] <signed transactions file> <report file>")
+	node := addNodeFlags(fs)
+	txFile := addTxFileFlags(fs, true, false)
+	if err := parseFlags(fs, args, 2); err != nil {
+		return err
+	}
+	opts, err := txFile.options(fs)
+	if err != nil {
+		return err
+	}
+
+	report, err := os.Create(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer report.Close()
+
+	clients := map[txs.Chain]issue.ChainClient{
+		txs.P: issue.NewPChainClient(node.platformClient()),
+		txs.X: issue.NewXChainClient(avm.NewClient(*node.uri, "X", *node.timeout)),
+	}
+	return issue.Broadcast(fs.Arg(0), opts.Chain, opts.InputEncoding, clients, report)
+}
@@ -0,0 +1,138 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/StephenButtolph/avalanche-tooling/hd"
+	"github.com/StephenButtolph/avalanche-tooling/issue"
+)
+
+const (
+	changeFixed         = "fixed"
+	changeRoundRobin    = "round-robin"
+	changeFresh         = "fresh"
+	changeSpendingOwner = "spending-owner"
+)
+
+// changeFlags are the flags of the commands that return change
+type changeFlags struct {
+	policy    *string
+	addresses *string
+	threshold *uint
+	locktime  *uint64
+	xpub      *string
+	index     *uint
+}
+
+func addChangeFlags(fs *flag.FlagSet) changeFlags {
+	return changeFlags{
+		policy:    fs.String("change-policy", changeFixed, fmt.Sprintf("who receives change: %q, %q, %q, or %q", changeFixed, changeRoundRobin, changeFresh, changeSpendingOwner)),
+		addresses: fs.String("change-address", "", "comma separated addresses that own the change of the fixed policy, defaults to the first address of the keys"),
+		threshold: fs.Uint("change-threshold", 1, "number of the -change-address addresses that must sign to spend the change"),
+		locktime:  fs.Uint64("change-locktime", 0, "unix time before which the change of the fixed policy can't be spent"),
+		xpub:      fs.String("change-xpub", "", "extended public key of the account whose change addresses the fresh policy derives"),
+		index:     fs.Uint("change-index", 0, "index of the first change address derived by the fresh policy"),
+	}
+}
+
+// validate reports invalid combinations of values as usage errors of [fs]
+func (f changeFlags) validate(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(set *flag.Flag) {
+		switch {
+		case *f.policy != changeFixed && (set.Name == "change-address" || set.Name == "change-threshold" || set.Name == "change-locktime"):
+			err = usageErrorf(fs, "-%s requires -change-policy %s", set.Name, changeFixed)
+		case *f.policy != changeFresh && (set.Name == "change-xpub" || set.Name == "change-index"):
+			err = usageErrorf(fs, "-%s requires -change-policy %s", set.Name, changeFresh)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	switch *f.policy {
+	case changeFixed:
+		if *f.addresses == "" && *f.threshold != 1 {
+			return usageErrorf(fs, "-change-threshold requires -change-address")
+		}
+	case changeFresh:
+		if *f.xpub == "" {
+			return usageErrorf(fs, "missing -change-xpub")
+		}
+		if *f.index >= hd.HardenedIndex {
+			return usageErrorf(fs, "-change-index must be less than %d", hd.HardenedIndex)
+		}
+	case changeRoundRobin, changeSpendingOwner:
+	default:
+		return usageErrorf(fs, "unknown change policy %q", *f.policy)
+	}
+	return nil
+}
+
+// changePolicy returns the policy chosen by the flags. Round robin cycles
+// through [keychain]. If nil is returned, change is sent to the first address
+// of the keys.
+func (f changeFlags) changePolicy(keychain []ids.ShortID) (issue.ChangePolicy, error) {
+	switch *f.policy {
+	case changeRoundRobin:
+		return issue.NewRoundRobinChange(keychain)
+	case changeFresh:
+		key, err := hd.ParseExtendedKey(*f.xpub)
+		if err != nil {
+			return nil, err
+		}
+		changeKey, err := key.Child(hd.ChangeBranch)
+		if err != nil {
+			return nil, err
+		}
+		return &issue.FreshChange{
+			Deriver: hd.NewDeriver(changeKey, uint32(*f.index)),
+		}, nil
+	case changeSpendingOwner:
+		return issue.SpendingOwnerChange{}, nil
+	}
+
+	if *f.addresses == "" && *f.locktime == 0 {
+		return nil, nil
+	}
+	addrs := keychain[:1]
+	if *f.addresses != "" {
+		addrs = nil
+		for _, addrStr := range strings.Split(*f.addresses, ",") {
+			addr, err := parseAddress(strings.TrimSpace(addrStr))
+			if err != nil {
+				return nil, fmt.Errorf("couldn't parse change address: %w", err)
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	return issue.NewFixedChange(addrs, uint32(*f.threshold), *f.locktime)
+}
+
+// logChangeIndex logs the -change-index that the next run of the fresh
+// [policy] should start at, so that change addresses are neither reused nor
+// skipped. If [issued] is false, the transactions were only built, and the
+// index to start at once they are issued is logged instead.
+func logChangeIndex(policy issue.ChangePolicy, issued bool) {
+	fresh, ok := policy.(*issue.FreshChange)
+	if !ok {
+		return
+	}
+	deriver, ok := fresh.Deriver.(*hd.Deriver)
+	if !ok {
+		return
+	}
+	if issued {
+		log.Printf("next unused change index is %d, pass it as -change-index to the next run", deriver.NextUnused())
+		return
+	}
+	log.Printf("change is sent to indices below %d, pass it as -change-index to the next run once the transactions are issued", deriver.NextIndex())
+}
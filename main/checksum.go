@@ -0,0 +1,51 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/StephenButtolph/avalanche-tooling/checksum"
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+)
+
+func runChecksum(args []string) error {
+	fs := newFlagSet("checksum", "<input file> <output file>")
+	inputEncodingStr := addEncodingFlag(fs, "in-encoding", txio.Auto, "encoding of the input file, raw-hex converted to checked-hex by a single worker is streamed in bounded memory")
+	outputEncodingStr := addEncodingFlag(fs, "out-encoding", txio.CheckedHex, "encoding of the output file")
+	workers := fs.Int("workers", 1, "number of records to convert concurrently")
+	if err := parseFlags(fs, args, 2); err != nil {
+		return err
+	}
+	inputEncoding, err := txio.ParseEncoding(*inputEncodingStr)
+	if err != nil {
+		return usageErrorf(fs, "%s", err)
+	}
+	outputEncoding, err := txio.ParseEncoding(*outputEncodingStr)
+	if err != nil {
+		return usageErrorf(fs, "%s", err)
+	}
+	if outputEncoding == txio.Auto {
+		return usageErrorf(fs, "-out-encoding must be explicit")
+	}
+	return checksum.Convert(fs.Arg(0), fs.Arg(1), inputEncoding, outputEncoding, *workers)
+}
+
+func runVerifyChecksum(args []string) error {
+	fs := newFlagSet("verify-checksum", "[-strip <output file>] <input file>")
+	strippedFilePath := fs.String("strip", "", "file to write the raw hex, without checksums, to if every line is valid")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	corruptLines, err := checksum.VerifyChecksum(fs.Arg(0), *strippedFilePath)
+	if err != nil {
+		return err
+	}
+	if len(corruptLines) > 0 {
+		return fmt.Errorf("%d corrupt lines: %v", len(corruptLines), corruptLines)
+	}
+	fmt.Println("all checksums are valid")
+	return nil
+}
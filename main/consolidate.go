@@ -0,0 +1,59 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"github.com/StephenButtolph/avalanche-tooling/issue"
+)
+
+func runConsolidate(args []string) error {
+	fs := newFlagSet("consolidate", "[-keystore <keystore file> | -remote-signer <uri>] [-target <utxos>] [-max-inputs <inputs>]")
+	node := addNodeFlags(fs)
+	signerFlags := addSignerFlags(fs)
+	target := fs.Int("target", 1, "number of UTXOs to merge the UTXOs of each asset and owner into")
+	maxInputs := fs.Int("max-inputs", 256, "most inputs a transaction may have")
+	feeAmount := fs.Uint64("fee", 0, "fee, in nAVAX, to pay per transaction, defaults to the node's tx fee")
+	change := addChangeFlags(fs)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	if err := change.validate(fs); err != nil {
+		return err
+	}
+
+	switch {
+	case *target <= 0:
+		return usageErrorf(fs, "-target must be positive")
+	case *maxInputs < 2:
+		return usageErrorf(fs, "-max-inputs must be at least 2")
+	}
+
+	s, closeSigner, err := signerFlags.signer(fs)
+	if err != nil {
+		return err
+	}
+	defer closeSigner()
+
+	p, err := node.networkParams(*feeAmount)
+	if err != nil {
+		return err
+	}
+	if p.fee > 0 && *maxInputs < 3 {
+		// Assets other than the fee asset need an extra input to pay the fee
+		return usageErrorf(fs, "-max-inputs must be at least 3 when a fee is paid")
+	}
+
+	changePolicy, err := change.changePolicy(s.Addresses())
+	if err != nil {
+		return err
+	}
+	defer logChangeIndex(changePolicy, true)
+
+	config := issue.ConsolidateConfig{
+		Target:    *target,
+		MaxInputs: *maxInputs,
+		Change:    changePolicy,
+	}
+	return issue.ConsolidateXChain(p.networkID, p.xChainID, p.xClient, s, p.feeAssetID, p.fee, config)
+}
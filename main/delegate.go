@@ -0,0 +1,90 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+
+	"github.com/StephenButtolph/avalanche-tooling/issue"
+)
+
+const (
+	// defaultStartDelay leaves time for the delegation to be accepted before
+	// it starts
+	defaultStartDelay       = time.Minute
+	defaultDelegateDuration = 14 * 24 * time.Hour
+)
+
+func runPDelegate(args []string) error {
+	fs := newFlagSet("p-delegate", "[-keystore <keystore file> | -remote-signer <uri>] -node-id <node ID> -weight <amount>")
+	node := addNodeFlags(fs)
+	signerFlags := addSignerFlags(fs)
+	nodeIDStr := fs.String("node-id", "", "validator to delegate to, such as NodeID-...")
+	weight := fs.Uint64("weight", 0, "amount, in nAVAX, to delegate")
+	start := fs.Uint64("start", 0, "unix time at which the delegation starts, defaults to a minute from now")
+	duration := fs.Duration("duration", defaultDelegateDuration, "how long the delegation lasts")
+	rewardAddrStr := fs.String("reward-address", "", "address that receives the reward, defaults to the first address of the keys")
+	fee := fs.Uint64("fee", 0, "fee, in nAVAX, to burn")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	switch {
+	case *nodeIDStr == "":
+		return usageErrorf(fs, "missing -node-id")
+	case *weight == 0:
+		return usageErrorf(fs, "missing -weight")
+	case *duration < time.Second:
+		return usageErrorf(fs, "-duration must be at least a second")
+	}
+	nodeID, err := ids.ShortFromPrefixedString(*nodeIDStr, constants.NodeIDPrefix)
+	if err != nil {
+		return usageErrorf(fs, "invalid -node-id: %s", err)
+	}
+	if *start == 0 {
+		*start = uint64(time.Now().Add(defaultStartDelay).Unix())
+	}
+
+	s, closeSigner, err := signerFlags.signer(fs)
+	if err != nil {
+		return err
+	}
+	defer closeSigner()
+
+	rewardAddr := s.Addresses()[0]
+	if *rewardAddrStr != "" {
+		rewardAddr, err = parseAddress(*rewardAddrStr)
+		if err != nil {
+			return fmt.Errorf("couldn't parse reward address: %w", err)
+		}
+	}
+
+	pClient := node.platformClient()
+	networkID, err := node.infoClient().GetNetworkID()
+	if err != nil {
+		return err
+	}
+	assetID, err := pClient.GetStakingAssetID(constants.PrimaryNetworkID)
+	if err != nil {
+		return err
+	}
+
+	txID, err := issue.Delegate(networkID, pClient, s, assetID, issue.Delegation{
+		NodeID:     nodeID,
+		Start:      *start,
+		End:        *start + uint64(duration.Seconds()),
+		Weight:     *weight,
+		RewardAddr: rewardAddr,
+		Fee:        *fee,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("delegated %d nAVAX to %s%s in %s\n", *weight, constants.NodeIDPrefix, nodeID, txID)
+	return nil
+}
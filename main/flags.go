@@ -0,0 +1,68 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+// txFileFlags describes how a transaction file should be read and written
+type txFileFlags struct {
+	chain          *string
+	inputEncoding  *string
+	outputEncoding *string
+}
+
+// addTxFileFlags registers the -chain and -in-encoding flags if [withInput] is
+// set and the -out-encoding flag if [withOutput] is set.
+func addTxFileFlags(fs *flag.FlagSet, withInput, withOutput bool) txFileFlags {
+	f := txFileFlags{}
+	if withInput {
+		f.chain = fs.String("chain", "auto", "chain of the transactions: P, X, C, or auto to detect each transaction's chain")
+		f.inputEncoding = addEncodingFlag(fs, "in-encoding", txio.Auto, "encoding of the input file")
+	}
+	if withOutput {
+		f.outputEncoding = addEncodingFlag(fs, "out-encoding", txio.CheckedHex, "encoding of the output file")
+	}
+	return f
+}
+
+func addEncodingFlag(fs *flag.FlagSet, name string, value txio.Encoding, usage string) *string {
+	encodings := strings.Join(txio.Names(), ", ")
+	return fs.String(name, value.String(), usage+", one of: "+encodings)
+}
+
+// options parses the flags, reporting invalid values as usage errors of [fs].
+func (f txFileFlags) options(fs *flag.FlagSet) (signer.Options, error) {
+	var (
+		opts signer.Options
+		err  error
+	)
+	if f.chain != nil {
+		opts.Chain, err = txs.ParseChain(*f.chain)
+		if err != nil {
+			return signer.Options{}, usageErrorf(fs, "%s", err)
+		}
+
+		opts.InputEncoding, err = txio.ParseEncoding(*f.inputEncoding)
+		if err != nil {
+			return signer.Options{}, usageErrorf(fs, "%s", err)
+		}
+	}
+	if f.outputEncoding != nil {
+		opts.OutputEncoding, err = txio.ParseEncoding(*f.outputEncoding)
+		if err != nil {
+			return signer.Options{}, usageErrorf(fs, "%s", err)
+		}
+		if opts.OutputEncoding == txio.Auto {
+			return signer.Options{}, usageErrorf(fs, "-out-encoding must be explicit")
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,36 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"os"
+
+	"github.com/StephenButtolph/avalanche-tooling/inspect"
+)
+
+func runInspect(args []string) error {
+	fs := newFlagSet("inspect", "<input file>")
+	txFile := addTxFileFlags(fs, true, false)
+	formatStr := fs.String("format", "both", "output format: text, json, or both")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	opts, err := txFile.options(fs)
+	if err != nil {
+		return err
+	}
+
+	var format inspect.Format
+	switch *formatStr {
+	case "text":
+		format = inspect.Text
+	case "json":
+		format = inspect.JSON
+	case "both":
+		format = inspect.TextAndJSON
+	default:
+		return usageErrorf(fs, "unknown format %q", *formatStr)
+	}
+	return inspect.Inspect(fs.Arg(0), opts.Chain, opts.InputEncoding, format, os.Stdout)
+}
@@ -0,0 +1,322 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+
+	"github.com/StephenButtolph/avalanche-tooling/issue"
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+)
+
+const (
+	flowXToX    = "x-to-x"
+	flowXExport = "x-export"
+	flowPImport = "p-import"
+)
+
+// sendFlags are the flags shared by the commands that send outputs to a file
+// of addresses
+type sendFlags struct {
+	node               nodeFlags
+	flow               *string
+	numUTXOsPerAddress *int
+	amountPerUTXO      *uint64
+	assetIDStr         *string
+	feeAmount          *uint64
+	manifest           *bool
+	mergeDuplicates    *bool
+	coinSelection      *string
+	maxInputs          *int
+	change             changeFlags
+}
+
+func addSendFlags(fs *flag.FlagSet) sendFlags {
+	return sendFlags{
+		node:               addNodeFlags(fs),
+		flow:               fs.String("flow", flowXToX, fmt.Sprintf("one of %q, %q, or %q", flowXToX, flowXExport, flowPImport)),
+		numUTXOsPerAddress: fs.Int("utxos-per-address", 1, "number of UTXOs to send to each address"),
+		amountPerUTXO:      fs.Uint64("amount", 0, "amount, in the asset's smallest denomination, of each UTXO"),
+		assetIDStr:         fs.String("asset-id", "", "asset to send, defaults to AVAX"),
+		feeAmount:          fs.Uint64("fee", 0, "fee, in nAVAX, to pay per transaction, defaults to the node's tx fee"),
+		manifest:           fs.Bool("manifest", false, "read the outputs from a CSV or JSON manifest instead of a file of addresses"),
+		mergeDuplicates:    fs.Bool("merge-duplicates", false, "sum manifest outputs with the same asset and owners instead of rejecting them"),
+		coinSelection:      fs.String("coin-selection", "largest-first", `how UTXOs are chosen: "largest-first", "smallest-first", "fewest-inputs", or "branch-and-bound"`),
+		maxInputs:          fs.Int("max-inputs", 0, "most inputs a transaction may have, unlimited if 0"),
+		change:             addChangeFlags(fs),
+	}
+}
+
+// validate reports invalid values as usage errors of [fs]
+func (f sendFlags) validate(fs *flag.FlagSet) error {
+	if *f.flow != flowXToX && *f.flow != flowXExport && *f.flow != flowPImport {
+		return usageErrorf(fs, "unknown flow %q", *f.flow)
+	}
+	if err := f.change.validate(fs); err != nil {
+		return err
+	}
+	if *f.manifest {
+		var err error
+		fs.Visit(func(set *flag.Flag) {
+			switch set.Name {
+			case "amount", "asset-id", "utxos-per-address":
+				err = usageErrorf(fs, "-%s can't be used with -manifest", set.Name)
+			}
+		})
+		return err
+	}
+
+	switch {
+	case *f.mergeDuplicates:
+		return usageErrorf(fs, "-merge-duplicates requires -manifest")
+	case *f.amountPerUTXO == 0:
+		return usageErrorf(fs, "missing -amount")
+	case *f.numUTXOsPerAddress <= 0:
+		return usageErrorf(fs, "-utxos-per-address must be positive")
+	default:
+		return nil
+	}
+}
+
+// spendConfig returns how the transactions are funded, reporting invalid
+// values as usage errors of [fs]
+func (f sendFlags) spendConfig(fs *flag.FlagSet) (issue.SpendConfig, error) {
+	if *f.maxInputs < 0 {
+		return issue.SpendConfig{}, usageErrorf(fs, "-max-inputs can't be negative")
+	}
+	selector, err := issue.ParseCoinSelector(*f.coinSelection)
+	if err != nil {
+		return issue.SpendConfig{}, usageErrorf(fs, "%s", err)
+	}
+	return issue.SpendConfig{
+		Selector:  selector,
+		MaxInputs: *f.maxInputs,
+	}, nil
+}
+
+// sendPlan is what the flows need to build the transactions of a send
+type sendPlan struct {
+	*networkParams
+	txOuts [][]*avax.TransferableOutput
+}
+
+// plan queries the node for the network's parameters and batches the outputs
+// sent to the addresses, or described by the manifest, in [outputsPath], so
+// that they can be funded as configured by [config].
+func (f sendFlags) plan(outputsPath string, config issue.SpendConfig) (*sendPlan, error) {
+	params, err := f.node.networkParams(*f.feeAmount)
+	if err != nil {
+		return nil, err
+	}
+	p := &sendPlan{networkParams: params}
+
+	maxInputs := config.MaxInputs
+	if maxInputs == 0 {
+		maxInputs = issue.DefaultMaxInputs
+	}
+	changeAddrs := config.MaxChangeAddrs()
+	maxOutputsSize, err := issue.OutputsSizeLimit(p.txKind(*f.flow), maxInputs, changeAddrs)
+	if err != nil {
+		return nil, err
+	}
+
+	if *f.manifest {
+		entries, err := issue.ReadManifest(outputsPath)
+		if err != nil {
+			return nil, err
+		}
+		outs, err := issue.ManifestOutputs(entries, p.networkID, p.feeAssetID, *f.mergeDuplicates)
+		if err != nil {
+			return nil, err
+		}
+		p.txOuts, err = issue.PackOutputs(outs, maxOutputsSize, changeAddrs)
+		return p, err
+	}
+
+	addresses, err := readAddresses(outputsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	assetID := p.feeAssetID
+	if *f.assetIDStr != "" {
+		assetID, err = ids.FromString(*f.assetIDStr)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse asset ID: %w", err)
+		}
+	}
+
+	p.txOuts, err = issue.BuildOutputs(addresses, *f.numUTXOsPerAddress, assetID, *f.amountPerUTXO, maxOutputsSize, changeAddrs)
+	return p, err
+}
+
+// txKind returns the kind of the transactions built by [flow]
+func (p *networkParams) txKind(flow string) issue.TxKind {
+	switch flow {
+	case flowXToX:
+		return &issue.BaseTxKind{
+			NetworkID: p.networkID,
+			ChainID:   p.xChainID,
+		}
+	case flowXExport:
+		return &issue.ExportTxKind{
+			NetworkID:          p.networkID,
+			ChainID:            p.xChainID,
+			DestinationChainID: constants.PlatformChainID,
+		}
+	default:
+		return &issue.ImportTxKind{
+			NetworkID:     p.networkID,
+			ChainID:       constants.PlatformChainID,
+			SourceChainID: p.xChainID,
+		}
+	}
+}
+
+func runIssue(args []string) error {
+	fs := newFlagSet("issue", "[-keystore <keystore file> | -remote-signer <uri>] -flow <flow> (-amount <amount> | -manifest) <addresses or manifest file>")
+	send := addSendFlags(fs)
+	signerFlags := addSignerFlags(fs)
+	maxInFlight := fs.Int("max-in-flight", 1, "number of builders to issue transactions at once, each spending from its own share of the UTXOs")
+	dryRunPath := fs.String("dry-run", "", "build and sign the transactions without issuing them, and write them with a report of their totals to this file")
+	journalPath := fs.String("journal", "", "file recording which transactions were sent, so that an interrupted run can be resumed by rerunning it")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	if err := send.validate(fs); err != nil {
+		return err
+	}
+
+	spendConfig, err := send.spendConfig(fs)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *maxInFlight <= 0:
+		return usageErrorf(fs, "-max-in-flight must be positive")
+	case *dryRunPath != "" && *journalPath != "":
+		return usageErrorf(fs, "-dry-run can't be used with -journal")
+	}
+
+	s, closeSigner, err := signerFlags.signer(fs)
+	if err != nil {
+		return err
+	}
+	defer closeSigner()
+
+	spendConfig.Change, err = send.change.changePolicy(s.Addresses())
+	if err != nil {
+		return err
+	}
+	defer logChangeIndex(spendConfig.Change, true)
+	p, err := send.plan(fs.Arg(0), spendConfig)
+	if err != nil {
+		return err
+	}
+
+	config := issue.SendConfig{
+		SpendConfig: spendConfig,
+		MaxInFlight: *maxInFlight,
+	}
+	if *journalPath != "" {
+		config.Journal, err = issue.OpenJournal(*journalPath, p.txOuts)
+		if err != nil {
+			return err
+		}
+		defer config.Journal.Close()
+	}
+
+	if *dryRunPath != "" {
+		config.DryRun = issue.NewDryRun(signer.AddressSet(s))
+	}
+
+	switch *send.flow {
+	case flowXToX:
+		err = issue.SendOutputsXToX(p.networkID, p.xChainID, p.xClient, s, p.txOuts, p.feeAssetID, p.fee, config)
+	case flowXExport:
+		err = issue.SendOutputsXToOther(p.networkID, p.xChainID, constants.PlatformChainID, p.xClient, s, p.txOuts, p.feeAssetID, p.fee, config)
+	default:
+		err = issue.SendOutputsOtherToP(p.networkID, constants.PlatformChainID, p.xChainID, p.pClient, s, p.txOuts, p.feeAssetID, p.fee, config)
+	}
+	if err != nil || config.DryRun == nil {
+		return err
+	}
+	return writeDryRunReport(*dryRunPath, config.DryRun.Report())
+}
+
+// writeDryRunReport writes [report] to [path] as JSON and prints its totals
+func writeDryRunReport(path string, report *issue.DryRunReport) error {
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	file, err := txio.CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+
+	if _, err := file.Write(append(reportJSON, '\n')); err != nil {
+		return err
+	}
+	if err := file.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d transactions consuming %d UTXOs written to %s\n", report.NumTxs, len(report.Consumed), path)
+	for _, sent := range report.Sent {
+		fmt.Printf("sends %s\n", sent)
+	}
+	for _, fee := range report.Fees {
+		fmt.Printf("burns %s\n", fee)
+	}
+	return nil
+}
+
+// readAddresses parses a file containing one bech32 address, such as
+// "X-avax1...", per line. Empty lines are ignored.
+func readAddresses(filePath string) ([]ids.ShortID, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var addresses []ids.ShortID
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		addr, err := parseAddress(line)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse address on line %d: %w", lineNumber, err)
+		}
+		addresses = append(addresses, addr)
+	}
+	return addresses, scanner.Err()
+}
+
+// parseAddress parses a bech32 address, such as "X-avax1..."
+func parseAddress(addrStr string) (ids.ShortID, error) {
+	_, _, addrBytes, err := formatting.ParseAddress(addrStr)
+	if err != nil {
+		return ids.ShortID{}, err
+	}
+	return ids.ToShortID(addrBytes)
+}
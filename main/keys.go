@@ -0,0 +1,297 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+
+	"github.com/StephenButtolph/avalanche-tooling/keystore"
+	"github.com/StephenButtolph/avalanche-tooling/remote"
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+)
+
+const (
+	defaultPassphraseEnv = "AVALANCHE_KEYSTORE_PASSPHRASE"
+	defaultTokenEnv      = "AVALANCHE_SIGNER_TOKEN"
+)
+
+var (
+	errNoPassphrase         = errors.New("no passphrase: set the passphrase environment variable or run from a terminal")
+	errEmptyPassphrase      = errors.New("passphrase must not be empty")
+	errMismatchedPassphrase = errors.New("passphrases don't match")
+	errNoToken              = errors.New("no token: set the token environment variable")
+)
+
+// signerFlags describe where the signing keys are held
+type signerFlags struct {
+	keystorePath  *string
+	passphraseEnv *string
+	remoteURI     *string
+	remoteTimeout *time.Duration
+	tokenEnv      *string
+}
+
+func addSignerFlags(fs *flag.FlagSet) signerFlags {
+	return signerFlags{
+		keystorePath:  fs.String("keystore", "", "encrypted keystore file holding the keys to sign with"),
+		passphraseEnv: addPassphraseEnvFlag(fs),
+		remoteURI:     fs.String("remote-signer", "", "URI of a remote signer to sign with instead of a keystore"),
+		remoteTimeout: fs.Duration("remote-signer-timeout", 10*time.Second, "timeout of requests to the remote signer"),
+		tokenEnv:      addTokenEnvFlag(fs, "remote-signer-token-env"),
+	}
+}
+
+func addTokenEnvFlag(fs *flag.FlagSet, name string) *string {
+	return fs.String(name, defaultTokenEnv, "environment variable holding the token shared by the remote signer and its clients")
+}
+
+// readToken returns the remote signer token held by the environment variable
+// [tokenEnv]
+func readToken(tokenEnv string) (string, error) {
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return "", fmt.Errorf("%w %s", errNoToken, tokenEnv)
+	}
+	return token, nil
+}
+
+func addPassphraseEnvFlag(fs *flag.FlagSet) *string {
+	return fs.String("passphrase-env", defaultPassphraseEnv, "environment variable holding the keystore passphrase, prompted for on the terminal if unset")
+}
+
+// signer returns the configured signer, reporting invalid flags as usage
+// errors of [fs]. The returned function must be called once the signer is no
+// longer needed.
+func (f signerFlags) signer(fs *flag.FlagSet) (signer.Signer, func(), error) {
+	switch {
+	case *f.keystorePath != "" && *f.remoteURI != "":
+		return nil, nil, usageErrorf(fs, "-keystore and -remote-signer are mutually exclusive")
+	case *f.remoteURI != "":
+		token, err := readToken(*f.tokenEnv)
+		if err != nil {
+			return nil, nil, err
+		}
+		s, err := remote.NewClient(*f.remoteURI, token, *f.remoteTimeout)
+		return s, func() {}, err
+	case *f.keystorePath != "":
+		s, err := loadKeystore(*f.keystorePath, *f.passphraseEnv)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	default:
+		return nil, nil, usageErrorf(fs, "missing -keystore or -remote-signer")
+	}
+}
+
+func loadKeystore(path, passphraseEnv string) (*signer.KeystoreSigner, error) {
+	passphrase, err := readPassphrase(passphraseEnv, false)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(passphrase)
+
+	return signer.LoadKeystore(path, passphrase)
+}
+
+// readPassphrase returns the passphrase held in the environment variable
+// [envName], or prompts for it on the terminal if the variable isn't set. The
+// variable is removed from the environment once read. If [confirm] is set, a
+// prompted passphrase must be entered twice.
+func readPassphrase(envName string, confirm bool) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(envName); envName != "" && ok {
+		if err := os.Unsetenv(envName); err != nil {
+			return nil, err
+		}
+		if passphrase == "" {
+			return nil, errEmptyPassphrase
+		}
+		return []byte(passphrase), nil
+	}
+
+	passphrase, err := readSecret("keystore passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errEmptyPassphrase
+	}
+	if !confirm {
+		return passphrase, nil
+	}
+
+	confirmation, err := readSecret("confirm passphrase: ")
+	defer zeroBytes(confirmation)
+	if err != nil {
+		zeroBytes(passphrase)
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, confirmation) {
+		zeroBytes(passphrase)
+		return nil, errMismatchedPassphrase
+	}
+	return passphrase, nil
+}
+
+// readSecret prompts for a value on the terminal without echoing it
+func readSecret(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errNoPassphrase
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return secret, err
+}
+
+func runKeystoreCreate(args []string) error {
+	fs := newFlagSet("keystore-create", "[-generate <n>] [-import] <keystore file>")
+	numGenerate := fs.Int("generate", 0, "number of new keys to generate")
+	importKeys := fs.Bool("import", false, "read CB58 encoded secret keys from stdin, one per line")
+	kdfName := fs.String("kdf", keystore.Scrypt.String(), fmt.Sprintf("key derivation function, one of: %s, %s", keystore.Scrypt, keystore.Argon2id))
+	passphraseEnv := addPassphraseEnvFlag(fs)
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	kdf, err := keystore.ParseKDF(*kdfName)
+	if err != nil {
+		return usageErrorf(fs, "%s", err)
+	}
+	switch {
+	case *numGenerate < 0:
+		return usageErrorf(fs, "-generate must not be negative")
+	case *numGenerate == 0 && !*importKeys:
+		return usageErrorf(fs, "expected -generate or -import")
+	}
+	if _, err := os.Stat(fs.Arg(0)); err == nil {
+		return fmt.Errorf("%q already exists", fs.Arg(0))
+	}
+
+	var keys []*crypto.PrivateKeySECP256K1R
+	defer func() {
+		for _, key := range keys {
+			zeroBytes(key.Bytes())
+		}
+	}()
+
+	if *importKeys {
+		keys, err = readSecretKeys()
+		if err != nil {
+			return err
+		}
+	}
+
+	secp := crypto.FactorySECP256K1R{}
+	for i := 0; i < *numGenerate; i++ {
+		skIntf, err := secp.NewPrivateKey()
+		if err != nil {
+			return err
+		}
+		keys = append(keys, skIntf.(*crypto.PrivateKeySECP256K1R))
+	}
+
+	passphrase, err := readPassphrase(*passphraseEnv, true)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(passphrase)
+
+	return keystore.Create(fs.Arg(0), keys, passphrase, kdf)
+}
+
+// readSecretKeys reads CB58 encoded secret keys from stdin. On a terminal,
+// each key is prompted for without being echoed until an empty line is
+// entered. The keys read before any error are returned so that they can be
+// zeroed.
+func readSecretKeys() ([]*crypto.PrivateKeySECP256K1R, error) {
+	var (
+		keys []*crypto.PrivateKeySECP256K1R
+		secp = crypto.FactorySECP256K1R{}
+	)
+	addKey := func(secretKey string) error {
+		secretKeyBytes, err := formatting.Decode(formatting.CB58, secretKey)
+		if err != nil {
+			return err
+		}
+		skIntf, err := secp.ToPrivateKey(secretKeyBytes)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, skIntf.(*crypto.PrivateKeySECP256K1R))
+		return nil
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		for {
+			secretKey, err := readSecret("secret key (empty to finish): ")
+			if err != nil {
+				return keys, err
+			}
+			if len(secretKey) == 0 {
+				return keys, nil
+			}
+			err = addKey(strings.TrimSpace(string(secretKey)))
+			zeroBytes(secretKey)
+			if err != nil {
+				return keys, err
+			}
+		}
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		secretKey := strings.TrimSpace(scanner.Text())
+		if secretKey == "" {
+			continue
+		}
+		if err := addKey(secretKey); err != nil {
+			return keys, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	return keys, scanner.Err()
+}
+
+func runKeystoreList(args []string) error {
+	fs := newFlagSet("keystore-list", "<keystore file>")
+	chainAlias := fs.String("chain", "X", "chain alias to prefix the addresses with")
+	hrp := fs.String("hrp", constants.MainnetHRP, "human readable part of the addresses")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	addrs, err := keystore.Addresses(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		addrStr, err := formatting.FormatAddress(*chainAlias, *hrp, addr.Bytes())
+		if err != nil {
+			return err
+		}
+		fmt.Println(addrStr)
+	}
+	return nil
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
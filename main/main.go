@@ -4,28 +4,138 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+)
 
-	"github.com/StephenButtolph/avalanche-tooling/checksum"
+const (
+	exitSuccess = 0
+	exitFailure = 1
+	exitUsage   = 2
 )
 
+// errUsage is returned by a command when it was invoked incorrectly. The
+// command is expected to have already printed a description of the problem.
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	// short description shown in the top level help text
+	summary string
+	// run executes the command with the arguments that followed its name
+	run func(args []string) error
+}
+
+var commands = map[string]command{
+	"audit-verify":     {summary: "verify the hash chain of a signing audit log", run: runAuditVerify},
+	"benched":          {summary: "display the benched validators and their stake", run: runBenched},
+	"broadcast":        {summary: "issue a file of signed transactions and report whether each was accepted", run: runBroadcast},
+	"build-unsigned":   {summary: "build the transactions of issue for watch-only addresses without signing them", run: runBuildUnsigned},
+	"checksum":         {summary: "convert a file of transactions between encodings, adding checksums by default", run: runChecksum},
+	"consolidate":      {summary: "merge the signer's X-chain UTXOs of each asset and owner into fewer UTXOs", run: runConsolidate},
+	"inspect":          {summary: "display the contents of a file of unsigned transactions", run: runInspect},
+	"issue":            {summary: "build, sign, and issue transactions to a set of addresses", run: runIssue},
+	"keystore-create":  {summary: "encrypt new or existing secret keys into a keystore file", run: runKeystoreCreate},
+	"keystore-list":    {summary: "display the addresses held in a keystore file", run: runKeystoreList},
+	"p-balances":       {summary: "display the unlocked and locked P-chain AVAX balance of each address", run: runPBalances},
+	"p-delegate":       {summary: "delegate P-chain AVAX, staking locked UTXOs before unlocked ones", run: runPDelegate},
+	"partial-create":   {summary: "create a partially signed file from unsigned transactions", run: runPartialCreate},
+	"partial-finalize": {summary: "verify a partially signed file is complete and write the signed transactions", run: runPartialFinalize},
+	"partial-sign":     {summary: "add signatures to a partially signed file", run: runPartialSign},
+	"sign":             {summary: "sign a file of unsigned P-chain, X-chain, or C-chain atomic transactions", run: runSign},
+	"signer-serve":     {summary: "serve signing requests for the keys of a keystore on a loopback address", run: runSignerServe},
+	"supply":           {summary: "display the amount of AVAX minted by staking rewards", run: runSupply},
+	"uptime":           {summary: "display the offline validators and their stake", run: runUptime},
+	"verify-checksum":  {summary: "verify, and optionally strip, the checksums of a file of transactions", run: runVerifyChecksum},
+}
+
 func main() {
-	// if len(os.Args) != 4 {
-	// 	log.Fatalf("expected secret key, input file, and output file to be provided as arguments")
-	// }
+	os.Exit(run(os.Args[1:]))
+}
 
-	// err := signer.Sign(os.Args[2], os.Args[3], os.Args[1])
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return exitSuccess
+	}
 
-	if len(os.Args) != 3 {
-		log.Fatalf("expected input file, and output file to be provided as arguments")
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return exitUsage
 	}
 
-	err := checksum.AddChecksum(os.Args[1], os.Args[2])
-	if err != nil {
-		log.Fatal(err)
+	switch err := cmd.run(args[1:]); {
+	case err == nil:
+		return exitSuccess
+	case errors.Is(err, flag.ErrHelp):
+		return exitSuccess
+	case errors.Is(err, errUsage):
+		return exitUsage
+	default:
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		return exitFailure
 	}
 }
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s <command> [flags] [args]\n\ncommands:\n", filepath.Base(os.Args[0]))
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-18s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(w, "\nrun '%s <command> -help' for the flags of a command\n", filepath.Base(os.Args[0]))
+}
+
+// newFlagSet returns a flag set for the command [name] that prints [usage]
+// followed by the command's flags when -help is requested.
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "usage: %s %s %s\n", filepath.Base(os.Args[0]), name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses [args] into [fs] and verifies that exactly [numArgs]
+// positional arguments remain.
+func parseFlags(fs *flag.FlagSet, args []string, numArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() != numArgs {
+		return usageErrorf(fs, "expected %d arguments but got %d", numArgs, fs.NArg())
+	}
+	return nil
+}
+
+// usageErrorf reports a problem with the invocation of the command that owns
+// [fs], prints the command's usage, and returns errUsage.
+func usageErrorf(fs *flag.FlagSet, format string, args ...interface{}) error {
+	fmt.Fprintf(fs.Output(), format+"\n", args...)
+	fs.Usage()
+	return errUsage
+}
@@ -0,0 +1,125 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/api/info"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+
+	"github.com/StephenButtolph/avalanche-tooling/benched"
+	"github.com/StephenButtolph/avalanche-tooling/supply"
+	"github.com/StephenButtolph/avalanche-tooling/uptime"
+)
+
+const (
+	defaultURI            = "http://127.0.0.1:9650"
+	defaultRequestTimeout = 10 * time.Second
+)
+
+type nodeFlags struct {
+	uri     *string
+	timeout *time.Duration
+}
+
+func addNodeFlags(fs *flag.FlagSet) nodeFlags {
+	return nodeFlags{
+		uri:     fs.String("uri", defaultURI, "URI of the node to query"),
+		timeout: fs.Duration("timeout", defaultRequestTimeout, "timeout of each API request"),
+	}
+}
+
+func (f nodeFlags) platformClient() *platformvm.Client {
+	return platformvm.NewClient(*f.uri, *f.timeout)
+}
+
+func (f nodeFlags) infoClient() *info.Client {
+	return info.NewClient(*f.uri, *f.timeout)
+}
+
+// networkParams are the parameters of the node's network that transactions
+// are built with
+type networkParams struct {
+	networkID  uint32
+	xChainID   ids.ID
+	xClient    *avm.Client
+	pClient    *platformvm.Client
+	feeAssetID ids.ID
+	fee        uint64
+}
+
+// networkParams queries the node for the parameters of its network. The fee
+// paid per transaction is [fee], or the node's tx fee if it is 0.
+func (f nodeFlags) networkParams(fee uint64) (*networkParams, error) {
+	infoClient := f.infoClient()
+	p := &networkParams{
+		xClient: avm.NewClient(*f.uri, "X", *f.timeout),
+		pClient: f.platformClient(),
+		fee:     fee,
+	}
+
+	var err error
+	p.networkID, err = infoClient.GetNetworkID()
+	if err != nil {
+		return nil, err
+	}
+	p.xChainID, err = infoClient.GetBlockchainID("X")
+	if err != nil {
+		return nil, err
+	}
+
+	avaxAsset, err := p.xClient.GetAssetDescription("AVAX")
+	if err != nil {
+		return nil, err
+	}
+	p.feeAssetID = avaxAsset.AssetID
+
+	if p.fee == 0 {
+		txFee, err := infoClient.GetTxFee()
+		if err != nil {
+			return nil, err
+		}
+		p.fee = uint64(txFee.TxFee)
+	}
+	return p, nil
+}
+
+func runSupply(args []string) error {
+	fs := newFlagSet("supply", "[-uri <node uri>]")
+	node := addNodeFlags(fs)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	minted, err := supply.GetAmountMinted(node.platformClient())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d nAVAX (%d AVAX) minted\n", minted, minted/units.Avax)
+	return nil
+}
+
+func runUptime(args []string) error {
+	fs := newFlagSet("uptime", "[-uri <node uri>]")
+	node := addNodeFlags(fs)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	return uptime.DisplayDown(node.platformClient())
+}
+
+func runBenched(args []string) error {
+	fs := newFlagSet("benched", "[-uri <node uri>]")
+	node := addNodeFlags(fs)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	return benched.DisplayBenched(node.infoClient(), node.platformClient())
+}
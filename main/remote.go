@@ -0,0 +1,118 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/StephenButtolph/avalanche-tooling/audit"
+	"github.com/StephenButtolph/avalanche-tooling/policy"
+	"github.com/StephenButtolph/avalanche-tooling/remote"
+)
+
+// shutdownTimeout bounds how long in-flight signing requests are waited for
+// once the server is interrupted
+const shutdownTimeout = 10 * time.Second
+
+func runSignerServe(args []string) error {
+	fs := newFlagSet("signer-serve", "-keystore <keystore file> [-listen <address>]")
+	keystorePath := fs.String("keystore", "", "encrypted keystore file holding the keys to sign with")
+	passphraseEnv := addPassphraseEnvFlag(fs)
+	tokenEnv := addTokenEnvFlag(fs, "token-env")
+	listenAddr := fs.String("listen", "127.0.0.1:9750", "loopback address to serve signing requests on")
+	auditLogPath := fs.String("audit-log", "", "hash chained log to append every signed transaction to")
+	policyPath := fs.String("policy", "", "JSON policy that every transaction must satisfy before it is signed")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	if *keystorePath == "" {
+		return usageErrorf(fs, "missing -keystore")
+	}
+
+	// Clients are only authenticated by a shared token, so the server must not
+	// be reachable from other machines.
+	host, _, err := net.SplitHostPort(*listenAddr)
+	if err != nil {
+		return usageErrorf(fs, "invalid -listen: %s", err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return usageErrorf(fs, "-listen must be a loopback address")
+	}
+
+	config := remote.HandlerConfig{}
+	config.Token, err = readToken(*tokenEnv)
+	if err != nil {
+		return err
+	}
+	if *policyPath != "" {
+		config.Policy, err = policy.Load(*policyPath)
+		if err != nil {
+			return err
+		}
+	}
+	if *auditLogPath != "" {
+		config.AuditLog, err = audit.Open(*auditLogPath)
+		if err != nil {
+			return err
+		}
+		defer config.AuditLog.Close()
+	}
+
+	s, err := loadKeystore(*keystorePath, *passphraseEnv)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	listener, err := net.Listen("tcp", *listenAddr)
+	if err != nil {
+		return err
+	}
+
+	config.Hosts = []string{*listenAddr, listener.Addr().String()}
+	handler, err := remote.NewHandler(s, config)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("serving %d addresses on http://%s", len(s.Addresses()), listener.Addr())
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Stop serving on an interrupt, rather than exiting, so that the keys are
+	// zeroed and the audit log is closed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return fmt.Errorf("serving signer: %w", err)
+	case <-ctx.Done():
+	}
+	log.Printf("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down signer: %w", err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving signer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"github.com/StephenButtolph/avalanche-tooling/audit"
+	"github.com/StephenButtolph/avalanche-tooling/policy"
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+)
+
+func runSign(args []string) error {
+	fs := newFlagSet("sign", "[-keystore <keystore file> | -remote-signer <uri>] -utxos <utxos file> <input file> <output file>")
+	signerFlags := addSignerFlags(fs)
+	utxosFilePath := fs.String("utxos", "", "file of hex encoded UTXOs consumed by the transactions")
+	auditLogPath := fs.String("audit-log", "", "hash chained log to append every signed transaction to")
+	policyPath := fs.String("policy", "", "JSON policy that every transaction must satisfy before it is signed")
+	txFile := addTxFileFlags(fs, true, true)
+	if err := parseFlags(fs, args, 2); err != nil {
+		return err
+	}
+	opts, err := txFile.options(fs)
+	if err != nil {
+		return err
+	}
+	if *utxosFilePath == "" {
+		return usageErrorf(fs, "missing -utxos")
+	}
+
+	if *policyPath != "" {
+		opts.Policy, err = policy.Load(*policyPath)
+		if err != nil {
+			return err
+		}
+	}
+	if *auditLogPath != "" {
+		opts.AuditLog, err = audit.Open(*auditLogPath)
+		if err != nil {
+			return err
+		}
+		defer opts.AuditLog.Close()
+	}
+
+	s, closeSigner, err := signerFlags.signer(fs)
+	if err != nil {
+		return err
+	}
+	defer closeSigner()
+
+	return signer.Sign(fs.Arg(0), fs.Arg(1), *utxosFilePath, s, opts)
+}
+
+func runPartialCreate(args []string) error {
+	fs := newFlagSet("partial-create", "-utxos <utxos file> <input file> <output file>")
+	utxosFilePath := fs.String("utxos", "", "file of hex encoded UTXOs consumed by the transactions")
+	txFile := addTxFileFlags(fs, true, false)
+	if err := parseFlags(fs, args, 2); err != nil {
+		return err
+	}
+	opts, err := txFile.options(fs)
+	if err != nil {
+		return err
+	}
+	if *utxosFilePath == "" {
+		return usageErrorf(fs, "missing -utxos")
+	}
+	return signer.CreatePartial(fs.Arg(0), fs.Arg(1), *utxosFilePath, opts)
+}
+
+func runPartialSign(args []string) error {
+	fs := newFlagSet("partial-sign", "[-keystore <keystore file> | -remote-signer <uri>] <input file> <output file>")
+	signerFlags := addSignerFlags(fs)
+	auditLogPath := fs.String("audit-log", "", "hash chained log to append every transaction that is given signatures to")
+	policyPath := fs.String("policy", "", "JSON policy that every transaction must satisfy before it is signed")
+	if err := parseFlags(fs, args, 2); err != nil {
+		return err
+	}
+
+	opts := signer.Options{}
+	var err error
+	if *policyPath != "" {
+		opts.Policy, err = policy.Load(*policyPath)
+		if err != nil {
+			return err
+		}
+	}
+	if *auditLogPath != "" {
+		opts.AuditLog, err = audit.Open(*auditLogPath)
+		if err != nil {
+			return err
+		}
+		defer opts.AuditLog.Close()
+	}
+
+	s, closeSigner, err := signerFlags.signer(fs)
+	if err != nil {
+		return err
+	}
+	defer closeSigner()
+
+	return signer.SignPartial(fs.Arg(0), fs.Arg(1), s, opts)
+}
+
+func runPartialFinalize(args []string) error {
+	fs := newFlagSet("partial-finalize", "<input file> <output file>")
+	txFile := addTxFileFlags(fs, false, true)
+	if err := parseFlags(fs, args, 2); err != nil {
+		return err
+	}
+	opts, err := txFile.options(fs)
+	if err != nil {
+		return err
+	}
+	return signer.FinalizePartial(fs.Arg(0), fs.Arg(1), opts)
+}
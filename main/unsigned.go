@@ -0,0 +1,120 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+
+	"github.com/StephenButtolph/avalanche-tooling/issue"
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+func runBuildUnsigned(args []string) error {
+	fs := newFlagSet("build-unsigned", "-from <addresses file> -utxos-out <utxos file> -flow <flow> (-amount <amount> | -manifest) <addresses or manifest file> <output file>")
+	send := addSendFlags(fs)
+	fromPath := fs.String("from", "", "file of the addresses whose UTXOs fund the transactions, change is sent to the first by default")
+	utxosPath := fs.String("utxos-out", "", "file to write the UTXOs consumed by the transactions to, as needed by sign -utxos")
+	txFile := addTxFileFlags(fs, false, true)
+	if err := parseFlags(fs, args, 2); err != nil {
+		return err
+	}
+	if err := send.validate(fs); err != nil {
+		return err
+	}
+	opts, err := txFile.options(fs)
+	if err != nil {
+		return err
+	}
+	config, err := send.spendConfig(fs)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *fromPath == "":
+		return usageErrorf(fs, "missing -from")
+	case *utxosPath == "":
+		return usageErrorf(fs, "missing -utxos-out")
+	}
+
+	from, err := readAddresses(*fromPath)
+	if err != nil {
+		return err
+	}
+	if len(from) == 0 {
+		return usageErrorf(fs, "-from contains no addresses")
+	}
+
+	config.Change, err = send.change.changePolicy(from)
+	if err != nil {
+		return err
+	}
+	defer logChangeIndex(config.Change, false)
+	p, err := send.plan(fs.Arg(0), config)
+	if err != nil {
+		return err
+	}
+
+	var (
+		unsignedTxs []txs.UnsignedTx
+		consumed    []*avax.UTXO
+	)
+	switch *send.flow {
+	case flowXToX:
+		unsignedTxs, consumed, err = issue.UnsignedOutputsXToX(p.networkID, p.xChainID, p.xClient, from, p.txOuts, p.feeAssetID, p.fee, config)
+	case flowXExport:
+		unsignedTxs, consumed, err = issue.UnsignedOutputsXToOther(p.networkID, p.xChainID, constants.PlatformChainID, p.xClient, from, p.txOuts, p.feeAssetID, p.fee, config)
+	default:
+		unsignedTxs, consumed, err = issue.UnsignedOutputsOtherToP(p.networkID, constants.PlatformChainID, p.xChainID, p.pClient, from, p.txOuts, p.feeAssetID, p.fee, config)
+	}
+	if err != nil {
+		return err
+	}
+
+	txBytes := make([][]byte, len(unsignedTxs))
+	for i, tx := range unsignedTxs {
+		txBytes[i] = tx.Bytes()
+	}
+	// The P-chain codec is able to encode every UTXO that can be spent
+	utxoBytes := make([][]byte, len(consumed))
+	for i, utxo := range consumed {
+		utxoBytes[i], err = platformvm.Codec.Marshal(txs.CodecVersion, utxo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := writeRecords(*utxosPath, txio.CheckedHex, utxoBytes); err != nil {
+		return err
+	}
+	if err := writeRecords(fs.Arg(1), opts.OutputEncoding, txBytes); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d unsigned transactions consuming %d UTXOs\n", len(txBytes), len(utxoBytes))
+	return nil
+}
+
+// writeRecords atomically replaces [path] with [records] in [encoding]
+func writeRecords(path string, encoding txio.Encoding, records [][]byte) error {
+	file, err := txio.CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+
+	writer := txio.NewWriter(file, encoding)
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return file.Commit()
+}
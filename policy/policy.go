@@ -0,0 +1,277 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package policy restricts which transactions the signer is willing to sign.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/utils/math"
+
+	"github.com/StephenButtolph/avalanche-tooling/inspect"
+)
+
+// avaxAlias may be used in place of the AVAX asset ID of the transaction's
+// network
+const avaxAlias = "AVAX"
+
+var (
+	// ErrViolation is wrapped by the errors returned for transactions that the
+	// policy doesn't allow
+	ErrViolation = errors.New("transaction violates the signing policy")
+
+	errInvalidTxType = errors.New("invalid transaction type")
+)
+
+// Policy is a set of rules that every transaction must satisfy. Rules that
+// are left unset don't restrict the transactions.
+type Policy struct {
+	// AllowedTxTypes are the transaction types that may be signed, such as
+	// "BaseTx" for any chain or "P.ImportTx" for a single chain.
+	AllowedTxTypes []string `json:"allowedTxTypes,omitempty"`
+	// AllowedNetworkIDs are the networks that transactions may target
+	AllowedNetworkIDs []uint32 `json:"allowedNetworkIDs,omitempty"`
+	// AllowedDestinations are the bech32 addresses, with or without a chain
+	// prefix, that outputs may be sent to. Outputs owned only by the signer's
+	// own addresses, such as change, are always allowed. Outputs to C-chain
+	// EVM addresses are never allowed if it is set.
+	AllowedDestinations []string `json:"allowedDestinations,omitempty"`
+	// MaxAmounts are the largest amounts of each asset, keyed by asset ID or
+	// "AVAX", that a transaction may send to addresses other than the
+	// signer's own.
+	MaxAmounts map[string]uint64 `json:"maxAmounts,omitempty"`
+	// MaxFee is the largest fee, in nAVAX, that a transaction may burn. If it
+	// is set, burning any asset other than AVAX is rejected.
+	MaxFee *uint64 `json:"maxFee,omitempty"`
+	// MaxLocktime is the latest unix time that any output may be locked
+	// until
+	MaxLocktime *uint64 `json:"maxLocktime,omitempty"`
+
+	destinations ids.ShortSet
+	maxAmounts   map[ids.ID]uint64
+	maxAVAX      *uint64
+}
+
+// Load reads the JSON encoded policy at [path]. Unknown fields are rejected
+// so that a misspelled rule can't silently allow everything.
+func Load(path string) (*Policy, error) {
+	policyBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Policy{}
+	decoder := json.NewDecoder(bytes.NewReader(policyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(p); err != nil {
+		return nil, fmt.Errorf("couldn't parse policy %q: %w", path, err)
+	}
+	if err := p.initialize(); err != nil {
+		return nil, fmt.Errorf("invalid policy %q: %w", path, err)
+	}
+	return p, nil
+}
+
+func (p *Policy) initialize() error {
+	for _, txType := range p.AllowedTxTypes {
+		if txType == "" || strings.Count(txType, ".") > 1 {
+			return fmt.Errorf("%w %q", errInvalidTxType, txType)
+		}
+	}
+
+	p.destinations = ids.NewShortSet(len(p.AllowedDestinations))
+	for _, addrStr := range p.AllowedDestinations {
+		addr, err := parseAddress(addrStr)
+		if err != nil {
+			return fmt.Errorf("destination %q: %w", addrStr, err)
+		}
+		p.destinations.Add(addr)
+	}
+
+	p.maxAmounts = make(map[ids.ID]uint64, len(p.MaxAmounts))
+	for assetIDStr, maxAmount := range p.MaxAmounts {
+		if strings.EqualFold(assetIDStr, avaxAlias) {
+			maxAmount := maxAmount
+			p.maxAVAX = &maxAmount
+			continue
+		}
+		assetID, err := ids.FromString(assetIDStr)
+		if err != nil {
+			return fmt.Errorf("asset %q: %w", assetIDStr, err)
+		}
+		p.maxAmounts[assetID] = maxAmount
+	}
+	return nil
+}
+
+// Check returns an error describing every rule that [tx] violates. Outputs
+// owned only by addresses in [signers] are treated as returning funds to the
+// signer.
+func (p *Policy) Check(tx *inspect.Summary, signers ids.ShortSet) error {
+	var violations []string
+	violationf := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf(format, args...))
+	}
+
+	if len(p.AllowedTxTypes) > 0 && !p.allowsTxType(tx) {
+		violationf("%s-chain %s isn't an allowed transaction type", tx.Chain, tx.Type)
+	}
+	if len(p.AllowedNetworkIDs) > 0 && !p.allowsNetworkID(tx.NetworkID) {
+		violationf("network %d isn't an allowed network", tx.NetworkID)
+	}
+
+	owners := make([]*inspect.Owners, 0, len(tx.Outputs)+2)
+	sent := make(map[ids.ID]uint64)
+	for i := range tx.Outputs {
+		out := &tx.Outputs[i]
+		owners = append(owners, &out.Owners)
+		if out.EVMAddress != "" && len(p.AllowedDestinations) > 0 {
+			violationf("output %d is sent to EVM address %s which isn't an allowed destination", i, out.EVMAddress)
+		}
+		if p.MaxLocktime != nil && out.StakeableLocktime > *p.MaxLocktime {
+			violationf("output %d is stakeable locked until %d which is after the max locktime %d", i, out.StakeableLocktime, *p.MaxLocktime)
+		}
+
+		ownedBySigner, err := ownedBy(&out.Owners, signers)
+		if err != nil {
+			violationf("output %d: %s", i, err)
+			continue
+		}
+		if ownedBySigner {
+			continue
+		}
+		newSent, err := math.Add64(sent[out.AssetID], out.Amount.Amount)
+		if err != nil {
+			violationf("amount of asset %s sent overflows", out.AssetID)
+			continue
+		}
+		sent[out.AssetID] = newSent
+	}
+	if tx.RewardsOwner != nil {
+		owners = append(owners, tx.RewardsOwner)
+	}
+	if tx.SubnetOwner != nil {
+		owners = append(owners, tx.SubnetOwner)
+	}
+
+	for _, owner := range owners {
+		if p.MaxLocktime != nil && owner.Locktime > *p.MaxLocktime {
+			violationf("owners %s are locked until %d which is after the max locktime %d", strings.Join(owner.Addresses, ", "), owner.Locktime, *p.MaxLocktime)
+		}
+		if len(p.AllowedDestinations) == 0 {
+			continue
+		}
+		for _, addrStr := range owner.Addresses {
+			addr, err := parseAddress(addrStr)
+			if err != nil {
+				violationf("couldn't parse destination %s: %s", addrStr, err)
+				continue
+			}
+			if !p.destinations.Contains(addr) && !signers.Contains(addr) {
+				violationf("%s isn't an allowed destination", addrStr)
+			}
+		}
+	}
+
+	avaxAssetID := tx.AVAXAssetID()
+	if p.maxAVAX != nil && avaxAssetID == ids.Empty && len(sent) > 0 {
+		violationf("the AVAX asset of network %d isn't known, so the AVAX limit can't be checked", tx.NetworkID)
+	}
+	for _, assetID := range sortedAssets(sent) {
+		maxAmount, limited := p.maxAmounts[assetID]
+		if assetID == avaxAssetID && p.maxAVAX != nil {
+			maxAmount, limited = *p.maxAVAX, true
+		}
+		if limited && sent[assetID] > maxAmount {
+			violationf("sends %d of asset %s which is more than the max of %d", sent[assetID], assetID, maxAmount)
+		}
+	}
+
+	if p.MaxFee != nil {
+		for _, fee := range tx.Fees {
+			switch {
+			case avaxAssetID == ids.Empty:
+				violationf("burns %d of asset %s but the AVAX asset of network %d isn't known", fee.Amount, fee.AssetID, tx.NetworkID)
+			case fee.AssetID != avaxAssetID:
+				violationf("burns %d of non-AVAX asset %s", fee.Amount, fee.AssetID)
+			case fee.Amount > *p.MaxFee:
+				violationf("fee of %d nAVAX is more than the max fee of %d nAVAX", fee.Amount, *p.MaxFee)
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: %s", ErrViolation, strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+func (p *Policy) allowsTxType(tx *inspect.Summary) bool {
+	qualified := fmt.Sprintf("%s.%s", tx.Chain, tx.Type)
+	for _, txType := range p.AllowedTxTypes {
+		if txType == tx.Type || txType == qualified {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) allowsNetworkID(networkID uint32) bool {
+	for _, allowed := range p.AllowedNetworkIDs {
+		if allowed == networkID {
+			return true
+		}
+	}
+	return false
+}
+
+// ownedBy returns true if every address of [owners] is in [addrs]
+func ownedBy(owners *inspect.Owners, addrs ids.ShortSet) (bool, error) {
+	if len(owners.Addresses) == 0 {
+		return false, nil
+	}
+	for _, addrStr := range owners.Addresses {
+		addr, err := parseAddress(addrStr)
+		if err != nil {
+			return false, err
+		}
+		if !addrs.Contains(addr) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// parseAddress parses a bech32 address that may be prefixed by a chain alias
+func parseAddress(addrStr string) (ids.ShortID, error) {
+	var (
+		addrBytes []byte
+		err       error
+	)
+	if strings.Contains(addrStr, "-") {
+		_, _, addrBytes, err = formatting.ParseAddress(addrStr)
+	} else {
+		_, addrBytes, err = formatting.ParseBech32(addrStr)
+	}
+	if err != nil {
+		return ids.ShortID{}, err
+	}
+	return ids.ToShortID(addrBytes)
+}
+
+func sortedAssets(amounts map[ids.ID]uint64) []ids.ID {
+	assetIDs := make([]ids.ID, 0, len(amounts))
+	for assetID := range amounts {
+		assetIDs = append(assetIDs, assetID)
+	}
+	ids.SortIDs(assetIDs)
+	return assetIDs
+}
@@ -0,0 +1,294 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/inspect"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+var (
+	signerAddr  = ids.ShortID{1}
+	allowedAddr = ids.ShortID{2}
+	thirdAddr   = ids.ShortID{3}
+)
+
+func formatTestAddress(t *testing.T, addr ids.ShortID) string {
+	t.Helper()
+
+	addrStr, err := formatting.FormatBech32(constants.FujiHRP, addr.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addrStr
+}
+
+// newTestOutput returns an output of [amount] of [assetID] owned by one of
+// [addrs] after [locktime]
+func newTestOutput(assetID ids.ID, amount, locktime uint64, addrs ...ids.ShortID) *avax.TransferableOutput {
+	owners := secp256k1fx.OutputOwners{
+		Locktime:  locktime,
+		Threshold: 1,
+		Addrs:     addrs,
+	}
+	owners.Sort()
+	return &avax.TransferableOutput{
+		Asset: avax.Asset{ID: assetID},
+		Out: &secp256k1fx.TransferOutput{
+			Amt:          amount,
+			OutputOwners: owners,
+		},
+	}
+}
+
+// newTestSummary describes a Fuji X-chain transaction that produces [outs] and
+// burns [fee] of [assetID]
+func newTestSummary(t *testing.T, assetID ids.ID, fee uint64, outs ...*avax.TransferableOutput) *inspect.Summary {
+	t.Helper()
+
+	consumed := fee
+	for _, out := range outs {
+		consumed += out.Out.Amount()
+	}
+	tx, err := txs.NewAVMTx(&avm.BaseTx{BaseTx: avax.BaseTx{
+		NetworkID:    constants.FujiID,
+		BlockchainID: ids.GenerateTestID(),
+		Outs:         outs,
+		Ins: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: assetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   consumed,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := inspect.Describe(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return summary
+}
+
+func TestCheck(t *testing.T) {
+	_, avaxAssetID, err := genesis.Genesis(constants.FujiID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherAssetID := ids.GenerateTestID()
+	allowedAddrStr := formatTestAddress(t, allowedAddr)
+
+	tests := []struct {
+		name              string
+		policy            string
+		tx                func(t *testing.T) *inspect.Summary
+		expectedViolation string
+	}{
+		{
+			name:   "allowed transaction type",
+			policy: `{"allowedTxTypes": ["X.BaseTx"]}`,
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 0)
+			},
+		},
+		{
+			name:   "transaction type of another chain",
+			policy: `{"allowedTxTypes": ["P.BaseTx"]}`,
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 0)
+			},
+			expectedViolation: "isn't an allowed transaction type",
+		},
+		{
+			name:   "network",
+			policy: `{"allowedNetworkIDs": [1]}`,
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 0)
+			},
+			expectedViolation: "isn't an allowed network",
+		},
+		{
+			name:   "allowed destination and change",
+			policy: fmt.Sprintf(`{"allowedDestinations": [%q]}`, "X-"+allowedAddrStr),
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 0,
+					newTestOutput(avaxAssetID, 100, 0, allowedAddr),
+					newTestOutput(avaxAssetID, 100, 0, signerAddr),
+				)
+			},
+		},
+		{
+			name:   "third-party destination",
+			policy: fmt.Sprintf(`{"allowedDestinations": [%q]}`, allowedAddrStr),
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 0,
+					newTestOutput(avaxAssetID, 100, 0, thirdAddr),
+				)
+			},
+			expectedViolation: "isn't an allowed destination",
+		},
+		{
+			name:   "destination shared by the signer and a third party",
+			policy: fmt.Sprintf(`{"allowedDestinations": [%q]}`, allowedAddrStr),
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 0,
+					newTestOutput(avaxAssetID, 100, 0, signerAddr, thirdAddr),
+				)
+			},
+			expectedViolation: "isn't an allowed destination",
+		},
+		{
+			name:   "EVM destination",
+			policy: fmt.Sprintf(`{"allowedDestinations": [%q]}`, allowedAddrStr),
+			tx: func(t *testing.T) *inspect.Summary {
+				tx, err := txs.NewAtomicTx(&txs.UnsignedImportTx{
+					NetworkID:    constants.FujiID,
+					BlockchainID: ids.GenerateTestID(),
+					SourceChain:  ids.GenerateTestID(),
+					ImportedInputs: []*avax.TransferableInput{{
+						UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+						Asset:  avax.Asset{ID: avaxAssetID},
+						In: &secp256k1fx.TransferInput{
+							Amt:   100,
+							Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+						},
+					}},
+					Outs: []txs.EVMOutput{{
+						Address: [txs.EVMAddressLen]byte{1},
+						Amount:  100,
+						AssetID: avaxAssetID,
+					}},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				summary, err := inspect.Describe(tx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return summary
+			},
+			expectedViolation: "sent to EVM address",
+		},
+		{
+			name:   "max amount excludes change",
+			policy: `{"maxAmounts": {"AVAX": 100}}`,
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 0,
+					newTestOutput(avaxAssetID, 100, 0, thirdAddr),
+					newTestOutput(avaxAssetID, 1000, 0, signerAddr),
+				)
+			},
+		},
+		{
+			name:   "max amount",
+			policy: `{"maxAmounts": {"AVAX": 100}}`,
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 0,
+					newTestOutput(avaxAssetID, 101, 0, thirdAddr),
+				)
+			},
+			expectedViolation: "more than the max of 100",
+		},
+		{
+			name:   "max amount includes outputs shared by the signer and a third party",
+			policy: `{"maxAmounts": {"AVAX": 100}}`,
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 0,
+					newTestOutput(avaxAssetID, 1000, 0, signerAddr, thirdAddr),
+				)
+			},
+			expectedViolation: "more than the max of 100",
+		},
+		{
+			name:   "max amount of another asset",
+			policy: fmt.Sprintf(`{"maxAmounts": {%q: 100}}`, otherAssetID),
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, otherAssetID, 0,
+					newTestOutput(otherAssetID, 101, 0, thirdAddr),
+				)
+			},
+			expectedViolation: "more than the max of 100",
+		},
+		{
+			name:   "max fee",
+			policy: `{"maxFee": 10}`,
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 11)
+			},
+			expectedViolation: "more than the max fee",
+		},
+		{
+			name:   "non-AVAX fee",
+			policy: `{"maxFee": 10}`,
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, otherAssetID, 1)
+			},
+			expectedViolation: "non-AVAX asset",
+		},
+		{
+			name:   "max locktime",
+			policy: `{"maxLocktime": 100}`,
+			tx: func(t *testing.T) *inspect.Summary {
+				return newTestSummary(t, avaxAssetID, 0,
+					newTestOutput(avaxAssetID, 100, 101, signerAddr),
+				)
+			},
+			expectedViolation: "after the max locktime",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(path, []byte(test.policy), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			p, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signers := ids.ShortSet{}
+			signers.Add(signerAddr)
+			err = p.Check(test.tx(t), signers)
+			if test.expectedViolation == "" {
+				if err != nil {
+					t.Fatalf("expected the transaction to be allowed, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrViolation) || !strings.Contains(err.Error(), test.expectedViolation) {
+				t.Fatalf("expected a violation containing %q, got %v", test.expectedViolation, err)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsUnknownRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"maxFees": 10}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected a misspelled rule to be rejected")
+	}
+}
@@ -0,0 +1,363 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package remote implements a signer.TxSigner that forwards signing requests
+// over HTTP, along with a server that exposes any signer.HashSigner over HTTP.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+
+	"github.com/StephenButtolph/avalanche-tooling/audit"
+	"github.com/StephenButtolph/avalanche-tooling/policy"
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+const (
+	addressesPath = "/v1/addresses"
+	signPath      = "/v1/sign"
+
+	// maxResponseSize bounds the size of the responses read by the client
+	maxResponseSize = 1 << 20
+	// maxRequestSize bounds the size of the requests read by the server. It
+	// fits the largest transaction that the nodes accept.
+	maxRequestSize = 1 << 20
+
+	bearerPrefix = "Bearer "
+	jsonType     = "application/json"
+)
+
+var (
+	_ signer.TxSigner = &Client{}
+
+	errInvalidSignature  = errors.New("remote signer returned a signature from the wrong key")
+	errNoToken           = errors.New("a token is required")
+	errUnauthorized      = errors.New("missing or wrong bearer token")
+	errWrongHost         = errors.New("request isn't addressed to the signer")
+	errWrongContentType  = errors.New("request body must be " + jsonType)
+	errWrongNumSigners   = errors.New("signers don't match the transaction's inputs")
+	errUnknownSigner     = errors.New("no key for address")
+	errWrongNumResponses = errors.New("remote signer returned the wrong number of signatures")
+)
+
+type addressesReply struct {
+	Addresses []ids.ShortID `json:"addresses"`
+}
+
+type signRequest struct {
+	// UnsignedTx is the unsigned transaction to sign
+	UnsignedTx []byte `json:"unsignedTx"`
+	// Signers are the addresses that should sign each input
+	Signers [][]ids.ShortID `json:"signers"`
+	// Complete is true if [Signers] are all the signatures that the
+	// transaction requires
+	Complete bool `json:"complete"`
+}
+
+type signReply struct {
+	// Signatures are the signatures of each of the requested signers
+	Signatures [][][]byte `json:"signatures"`
+}
+
+type errorReply struct {
+	Error string `json:"error"`
+}
+
+// Client is a signer whose keys are held by a remote signing server
+type Client struct {
+	uri       string
+	token     string
+	client    *http.Client
+	addresses []ids.ShortID
+}
+
+// NewClient connects to the signing server at [uri], authenticating with
+// [token], and fetches the addresses it can sign for.
+func NewClient(uri, token string, timeout time.Duration) (*Client, error) {
+	if token == "" {
+		return nil, errNoToken
+	}
+	c := &Client{
+		uri:    strings.TrimSuffix(uri, "/"),
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+
+	reply := addressesReply{}
+	if err := c.call(http.MethodGet, addressesPath, nil, &reply); err != nil {
+		return nil, err
+	}
+	c.addresses = reply.Addresses
+	return c, nil
+}
+
+func (c *Client) Addresses() []ids.ShortID { return c.addresses }
+
+// SignTxBytes requests the signatures of [unsignedTxBytes] from the server.
+// Each signature is verified to have been produced by the key of its signer
+// before it is returned.
+func (c *Client) SignTxBytes(unsignedTxBytes []byte, signers [][]ids.ShortID, complete bool) ([][][]byte, error) {
+	reply := signReply{}
+	err := c.call(http.MethodPost, signPath, &signRequest{
+		UnsignedTx: unsignedTxBytes,
+		Signers:    signers,
+		Complete:   complete,
+	}, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if len(reply.Signatures) != len(signers) {
+		return nil, errWrongNumResponses
+	}
+
+	secp := crypto.FactorySECP256K1R{}
+	hash := hashing.ComputeHash256(unsignedTxBytes)
+	for i, inputSigs := range reply.Signatures {
+		if len(inputSigs) != len(signers[i]) {
+			return nil, errWrongNumResponses
+		}
+		for j, sig := range inputSigs {
+			pk, err := secp.RecoverHashPublicKey(hash, sig)
+			if err != nil {
+				return nil, fmt.Errorf("remote signer returned an invalid signature: %w", err)
+			}
+			if pk.Address() != signers[i][j] {
+				return nil, errInvalidSignature
+			}
+		}
+	}
+	return reply.Signatures, nil
+}
+
+func (c *Client) call(method, path string, args, reply interface{}) error {
+	var body io.Reader
+	if args != nil {
+		argsBytes, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(argsBytes)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, c.uri+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", bearerPrefix+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", jsonType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize))
+	if resp.StatusCode != http.StatusOK {
+		errReply := errorReply{}
+		if err := decoder.Decode(&errReply); err != nil || errReply.Error == "" {
+			return fmt.Errorf("remote signer responded with %s", resp.Status)
+		}
+		return fmt.Errorf("remote signer: %s", errReply.Error)
+	}
+	return decoder.Decode(reply)
+}
+
+// HandlerConfig configures the checks that a signing server makes before
+// signing
+type HandlerConfig struct {
+	// Token must be presented as a bearer token by every request
+	Token string
+	// Hosts are the values of the Host header that requests may have, such as
+	// the address that the server listens on. Requests addressed to any other
+	// host, as sent by a page using DNS rebinding, are rejected.
+	Hosts []string
+	// Policy, if non-nil, must allow every transaction before it is signed
+	Policy *policy.Policy
+	// AuditLog, if non-nil, has an entry appended for every signed
+	// transaction before its signatures are returned
+	AuditLog *audit.Log
+}
+
+type handler struct {
+	signer signer.HashSigner
+	addrs  ids.ShortSet
+	config HandlerConfig
+	hosts  map[string]bool
+
+	// lock serializes signing, so that the audit log is appended to in the
+	// order that signatures are returned
+	lock sync.Mutex
+}
+
+// NewHandler returns an http.Handler that serves signing requests with [s].
+// Only requests authenticated by the token of [config] are served, and only
+// transactions allowed by its policy are signed.
+func NewHandler(s signer.HashSigner, config HandlerConfig) (http.Handler, error) {
+	if config.Token == "" {
+		return nil, errNoToken
+	}
+	h := &handler{
+		signer: s,
+		addrs:  signer.AddressSet(s),
+		config: config,
+		hosts:  make(map[string]bool, len(config.Hosts)),
+	}
+	for _, host := range config.Hosts {
+		h.hosts[strings.ToLower(host)] = true
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(addressesPath, h.addresses)
+	mux.HandleFunc(signPath, h.sign)
+	return h.authenticate(mux), nil
+}
+
+// authenticate rejects requests to [next] that weren't sent by a client
+// holding the token
+func (h *handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.hosts[strings.ToLower(r.Host)] {
+			writeError(w, http.StatusForbidden, fmt.Errorf("%w: %q", errWrongHost, r.Host))
+			return
+		}
+
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, bearerPrefix) ||
+			subtle.ConstantTimeCompare([]byte(auth[len(bearerPrefix):]), []byte(h.config.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != jsonType {
+				writeError(w, http.StatusUnsupportedMediaType, errWrongContentType)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *handler) addresses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("unexpected method %s", r.Method))
+		return
+	}
+	writeReply(w, &addressesReply{Addresses: h.signer.Addresses()})
+}
+
+func (h *handler) sign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("unexpected method %s", r.Method))
+		return
+	}
+
+	req := signRequest{}
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	unsignedTx, err := txs.Parse(txs.Unknown, req.UnsignedTx)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ins, err := unsignedTx.Inputs()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Signers) != len(ins) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: %d signers for %d inputs", errWrongNumSigners, len(req.Signers), len(ins)))
+		return
+	}
+	for _, inputSigners := range req.Signers {
+		for _, addr := range inputSigners {
+			if !h.addrs.Contains(addr) {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("%w %s", errUnknownSigner, addr))
+				return
+			}
+		}
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	opts := signer.Options{
+		Policy:   h.config.Policy,
+		AuditLog: h.config.AuditLog,
+	}
+	summary, err := signer.Review(unsignedTx, h.addrs, opts)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	hash := hashing.ComputeHash256(unsignedTx.Bytes())
+	sigs := make([][][]byte, len(req.Signers))
+	for i, inputSigners := range req.Signers {
+		sigs[i] = make([][]byte, len(inputSigners))
+		for j, addr := range inputSigners {
+			sigs[i][j], err = h.signer.SignHash(addr, hash)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+		}
+	}
+
+	if h.config.AuditLog != nil {
+		var signedTxBytes []byte
+		if req.Complete {
+			creds, err := signer.NewCredentials(sigs)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			signedTxBytes, err = unsignedTx.Attach(creds)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		if err := signer.LogSigned(h.config.AuditLog, unsignedTx, summary, signedTxBytes, req.Signers); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("couldn't append to the audit log: %w", err))
+			return
+		}
+	}
+	writeReply(w, &signReply{Signatures: sigs})
+}
+
+func writeReply(w http.ResponseWriter, reply interface{}) {
+	w.Header().Set("Content-Type", jsonType)
+	_ = json.NewEncoder(w).Encode(reply)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", jsonType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(&errorReply{Error: err.Error()})
+}
@@ -0,0 +1,315 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/audit"
+	"github.com/StephenButtolph/avalanche-tooling/policy"
+	"github.com/StephenButtolph/avalanche-tooling/signer"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+const (
+	testToken = "secret"
+	testHost  = "example.com"
+)
+
+func newTestSigner(t *testing.T) (signer.HashSigner, ids.ShortID) {
+	t.Helper()
+
+	sk, err := (&crypto.FactorySECP256K1R{}).NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keychain := secp256k1fx.NewKeychain()
+	keychain.Add(sk.(*crypto.PrivateKeySECP256K1R))
+	return signer.NewKeychainSigner(keychain), sk.PublicKey().Address()
+}
+
+// newTestTx returns an X-chain transaction on [networkID] with a single input
+// owned by [addr]
+func newTestTx(t *testing.T, networkID uint32, addr ids.ShortID) txs.UnsignedTx {
+	t.Helper()
+
+	assetID := ids.GenerateTestID()
+	tx, err := txs.NewAVMTx(&avm.BaseTx{BaseTx: avax.BaseTx{
+		NetworkID:    networkID,
+		BlockchainID: ids.GenerateTestID(),
+		Outs: []*avax.TransferableOutput{{
+			Asset: avax.Asset{ID: assetID},
+			Out: &secp256k1fx.TransferOutput{
+				Amt: 1000,
+				OutputOwners: secp256k1fx.OutputOwners{
+					Threshold: 1,
+					Addrs:     []ids.ShortID{addr},
+				},
+			},
+		}},
+		Ins: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: assetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   2000,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func newTestRequest(t *testing.T, body interface{}) *http.Request {
+	t.Helper()
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "http://"+testHost+signPath, bytes.NewReader(bodyBytes))
+	req.Header.Set("Authorization", bearerPrefix+testToken)
+	req.Header.Set("Content-Type", jsonType)
+	return req
+}
+
+func writeTestPolicy(t *testing.T, p string) *policy.Policy {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(p), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := policy.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+func TestNewHandlerRequiresToken(t *testing.T) {
+	s, _ := newTestSigner(t)
+	if _, err := NewHandler(s, HandlerConfig{Hosts: []string{testHost}}); err != errNoToken {
+		t.Fatalf("expected %v, got %v", errNoToken, err)
+	}
+}
+
+func TestHandlerRejects(t *testing.T) {
+	s, addr := newTestSigner(t)
+	tx := newTestTx(t, 5, addr)
+	validRequest := &signRequest{
+		UnsignedTx: tx.Bytes(),
+		Signers:    [][]ids.ShortID{{addr}},
+		Complete:   true,
+	}
+
+	tests := []struct {
+		name           string
+		modify         func(req *http.Request) *http.Request
+		policy         string
+		expectedStatus int
+	}{
+		{
+			name: "missing token",
+			modify: func(req *http.Request) *http.Request {
+				req.Header.Del("Authorization")
+				return req
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong token",
+			modify: func(req *http.Request) *http.Request {
+				req.Header.Set("Authorization", bearerPrefix+"wrong")
+				return req
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "token without bearer scheme",
+			modify: func(req *http.Request) *http.Request {
+				req.Header.Set("Authorization", testToken)
+				return req
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong host",
+			modify: func(req *http.Request) *http.Request {
+				req.Host = "attacker.example"
+				return req
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "form content type",
+			modify: func(req *http.Request) *http.Request {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "missing content type",
+			modify: func(req *http.Request) *http.Request {
+				req.Header.Del("Content-Type")
+				return req
+			},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "raw hash",
+			modify: func(*http.Request) *http.Request {
+				return newTestRequest(t, map[string]interface{}{
+					"address": addr,
+					"hash":    make([]byte, 32),
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unparsable transaction",
+			modify: func(*http.Request) *http.Request {
+				return newTestRequest(t, &signRequest{
+					UnsignedTx: make([]byte, 32),
+					Signers:    [][]ids.ShortID{{addr}},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "wrong number of signers",
+			modify: func(*http.Request) *http.Request {
+				return newTestRequest(t, &signRequest{
+					UnsignedTx: tx.Bytes(),
+					Signers:    [][]ids.ShortID{{addr}, {addr}},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unknown signer",
+			modify: func(*http.Request) *http.Request {
+				return newTestRequest(t, &signRequest{
+					UnsignedTx: tx.Bytes(),
+					Signers:    [][]ids.ShortID{{ids.GenerateTestShortID()}},
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "policy violation",
+			modify:         func(req *http.Request) *http.Request { return req },
+			policy:         `{"allowedNetworkIDs": [1]}`,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			auditPath := filepath.Join(t.TempDir(), "audit.log")
+			auditLog, err := audit.Open(auditPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer auditLog.Close()
+
+			config := HandlerConfig{
+				Token:    testToken,
+				Hosts:    []string{testHost},
+				AuditLog: auditLog,
+			}
+			if test.policy != "" {
+				config.Policy = writeTestPolicy(t, test.policy)
+			}
+			handler, err := NewHandler(s, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, test.modify(newTestRequest(t, validRequest)))
+			if w.Code != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", test.expectedStatus, w.Code, w.Body)
+			}
+
+			numEntries, _, err := audit.Verify(auditPath, ids.Empty)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if numEntries != 0 {
+				t.Fatalf("expected no audit entries, got %d", numEntries)
+			}
+		})
+	}
+}
+
+func TestClientSignsAndAudits(t *testing.T) {
+	s, addr := newTestSigner(t)
+
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := audit.Open(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+
+	server := httptest.NewUnstartedServer(nil)
+	handler, err := NewHandler(s, HandlerConfig{
+		Token:    testToken,
+		Hosts:    []string{server.Listener.Addr().String()},
+		Policy:   writeTestPolicy(t, `{"allowedNetworkIDs": [5]}`),
+		AuditLog: auditLog,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Config.Handler = handler
+	server.Start()
+	defer server.Close()
+
+	if _, err := NewClient(server.URL, "wrong", time.Second); err == nil {
+		t.Fatal("expected a client with the wrong token to be rejected")
+	}
+
+	client, err := NewClient(server.URL, testToken, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs := client.Addresses(); len(addrs) != 1 || addrs[0] != addr {
+		t.Fatalf("expected addresses [%s], got %v", addr, addrs)
+	}
+	if _, ok := signer.Signer(client).(signer.HashSigner); ok {
+		t.Fatal("expected the client not to sign raw hashes")
+	}
+
+	tx := newTestTx(t, 5, addr)
+	for i := 0; i < 2; i++ {
+		if _, err := signer.SignTx(tx, client, [][]ids.ShortID{{addr}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	numEntries, _, err := audit.Verify(auditPath, ids.Empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numEntries != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", numEntries)
+	}
+}
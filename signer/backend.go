@@ -0,0 +1,207 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package signer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/keystore"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+var (
+	errUnknownAddress = errors.New("no key for address")
+	errWatchOnly      = errors.New("watch-only signer holds no keys")
+	errCantSign       = errors.New("signer can't sign")
+)
+
+// Signer produces signatures on behalf of a set of addresses. Implementations
+// sign either hashes, as a HashSigner, or whole transactions, as a TxSigner.
+type Signer interface {
+	// Addresses returns the addresses that this signer can sign for. The
+	// order is stable, so the first address may be used as a default.
+	Addresses() []ids.ShortID
+}
+
+// HashSigner is a Signer that signs any hash, such as one holding the keys in
+// memory
+type HashSigner interface {
+	Signer
+
+	// SignHash returns the recoverable secp256k1 signature of [hash] by the
+	// key of [addr].
+	SignHash(addr ids.ShortID, hash []byte) ([]byte, error)
+}
+
+// TxSigner is a Signer that is shown the transactions it signs, rather than
+// only their hashes, so that it can check them before signing, such as one
+// forwarding the requests to another process
+type TxSigner interface {
+	Signer
+
+	// SignTxBytes returns the signatures of the unsigned transaction
+	// [unsignedTxBytes] by the addresses listed for each of its inputs in
+	// [signers]. If [complete] is false, [signers] are only some of the
+	// signatures that the transaction requires.
+	SignTxBytes(unsignedTxBytes []byte, signers [][]ids.ShortID, complete bool) ([][][]byte, error)
+}
+
+// AddressSet returns the addresses of [s] as a set
+func AddressSet(s Signer) ids.ShortSet {
+	addrs := s.Addresses()
+	set := ids.NewShortSet(len(addrs))
+	set.Add(addrs...)
+	return set
+}
+
+type keychainSigner struct {
+	keychain *secp256k1fx.Keychain
+}
+
+// NewKeychainSigner returns a signer that holds the keys of [keychain] in
+// memory.
+func NewKeychainSigner(keychain *secp256k1fx.Keychain) HashSigner {
+	return &keychainSigner{keychain: keychain}
+}
+
+func (s *keychainSigner) Addresses() []ids.ShortID {
+	addrs := make([]ids.ShortID, len(s.keychain.Keys))
+	for i, key := range s.keychain.Keys {
+		addrs[i] = key.PublicKey().Address()
+	}
+	return addrs
+}
+
+func (s *keychainSigner) SignHash(addr ids.ShortID, hash []byte) ([]byte, error) {
+	key, ok := s.keychain.Get(addr)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", errUnknownAddress, addr)
+	}
+	return key.SignHash(hash)
+}
+
+type watchOnlySigner struct {
+	addrs []ids.ShortID
+}
+
+// NewWatchOnly returns a signer for [addrs] that holds no keys. It can be used
+// to build transactions that are signed elsewhere, but fails to sign.
+func NewWatchOnly(addrs []ids.ShortID) Signer {
+	return &watchOnlySigner{addrs: addrs}
+}
+
+func (s *watchOnlySigner) Addresses() []ids.ShortID { return s.addrs }
+
+func (*watchOnlySigner) SignHash(ids.ShortID, []byte) ([]byte, error) {
+	return nil, errWatchOnly
+}
+
+// KeystoreSigner signs with the keys of a decrypted keystore file
+type KeystoreSigner struct {
+	HashSigner
+	keys *keystore.Keys
+}
+
+// LoadKeystore decrypts the keystore at [path] with [passphrase]. The signer
+// should be closed once it is no longer needed to zero the keys.
+func LoadKeystore(path string, passphrase []byte) (*KeystoreSigner, error) {
+	keys, err := keystore.Load(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &KeystoreSigner{
+		HashSigner: NewKeychainSigner(keys.Keychain),
+		keys:       keys,
+	}, nil
+}
+
+// Close zeroes the keys. The signer must not be used afterwards.
+func (s *KeystoreSigner) Close() {
+	s.keys.Zero()
+}
+
+// Credentials returns one credential for each entry of [signers], holding the
+// signatures of the listed addresses over [unsignedTxBytes].
+func Credentials(s Signer, unsignedTxBytes []byte, signers [][]ids.ShortID) ([]*secp256k1fx.Credential, error) {
+	sigs, err := signatures(s, unsignedTxBytes, signers, true)
+	if err != nil {
+		return nil, fmt.Errorf("problem generating credential: %w", err)
+	}
+	return NewCredentials(sigs)
+}
+
+// NewCredentials returns one credential for each entry of [sigs], holding its
+// signatures in order
+func NewCredentials(sigs [][][]byte) ([]*secp256k1fx.Credential, error) {
+	creds := make([]*secp256k1fx.Credential, len(sigs))
+	for i, inputSigs := range sigs {
+		cred := &secp256k1fx.Credential{
+			Sigs: make([][crypto.SECP256K1RSigLen]byte, len(inputSigs)),
+		}
+		for j, sig := range inputSigs {
+			if len(sig) != crypto.SECP256K1RSigLen {
+				return nil, fmt.Errorf("problem generating credential: signature has length %d", len(sig))
+			}
+			copy(cred.Sigs[j][:], sig)
+		}
+		creds[i] = cred
+	}
+	return creds, nil
+}
+
+// signatures returns the signatures of [unsignedTxBytes] by the addresses
+// listed for each input in [signers]. A TxSigner is given the whole
+// transaction, and a HashSigner signs its hash.
+func signatures(s Signer, unsignedTxBytes []byte, signers [][]ids.ShortID, complete bool) ([][][]byte, error) {
+	if txSigner, ok := s.(TxSigner); ok {
+		sigs, err := txSigner.SignTxBytes(unsignedTxBytes, signers, complete)
+		if err != nil {
+			return nil, err
+		}
+		if len(sigs) != len(signers) {
+			return nil, fmt.Errorf("%w: got signatures for %d of %d inputs", errMismatchedSignatures, len(sigs), len(signers))
+		}
+		for i, inputSigs := range sigs {
+			if len(inputSigs) != len(signers[i]) {
+				return nil, fmt.Errorf("%w: input %d has %d of %d signatures", errMismatchedSignatures, i, len(inputSigs), len(signers[i]))
+			}
+		}
+		return sigs, nil
+	}
+
+	hashSigner, ok := s.(HashSigner)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T signs neither hashes nor transactions", errCantSign, s)
+	}
+	hash := hashing.ComputeHash256(unsignedTxBytes)
+	sigs := make([][][]byte, len(signers))
+	for i, addrs := range signers {
+		sigs[i] = make([][]byte, len(addrs))
+		for j, addr := range addrs {
+			sig, err := hashSigner.SignHash(addr, hash)
+			if err != nil {
+				return nil, err
+			}
+			sigs[i][j] = sig
+		}
+	}
+	return sigs, nil
+}
+
+// SignTx returns the signed transaction bytes of [tx] after signing each of
+// its inputs with the addresses listed in the corresponding entry of
+// [signers].
+func SignTx(tx txs.UnsignedTx, s Signer, signers [][]ids.ShortID) ([]byte, error) {
+	creds, err := Credentials(s, tx.Bytes(), signers)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Attach(creds)
+}
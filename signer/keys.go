@@ -0,0 +1,122 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package signer
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+)
+
+// requirement describes the signatures needed to authorize an input.
+type requirement struct {
+	// threshold is the number of signatures the UTXO owners require
+	threshold uint32
+	// signers are the addresses that must sign, in the order that their
+	// signatures must appear in the input's credential
+	signers []ids.ShortID
+}
+
+// inputRequirements returns, for each of the [ins], the addresses that must
+// sign the input. The owners of each input are looked up in [utxos].
+func inputRequirements(
+	ins []*avax.TransferableInput,
+	utxos map[ids.ID]*avax.UTXO,
+) ([]requirement, error) {
+	requirements := make([]requirement, len(ins))
+	for i, in := range ins {
+		inputID := in.InputID()
+		utxo, ok := utxos[inputID]
+		if !ok {
+			return nil, fmt.Errorf("missing UTXO %s consumed by input %d", inputID, i)
+		}
+
+		owners, err := utxoOwners(utxo)
+		if err != nil {
+			return nil, fmt.Errorf("UTXO %s: %w", inputID, err)
+		}
+
+		input, err := secpInput(in.In)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+
+		signers := make([]ids.ShortID, len(input.SigIndices))
+		for j, sigIndex := range input.SigIndices {
+			if sigIndex >= uint32(len(owners.Addrs)) {
+				return nil, fmt.Errorf("input %d references signer %d but UTXO %s only has %d owners",
+					i,
+					sigIndex,
+					inputID,
+					len(owners.Addrs),
+				)
+			}
+			signers[j] = owners.Addrs[sigIndex]
+		}
+		requirements[i] = requirement{
+			threshold: owners.Threshold,
+			signers:   signers,
+		}
+	}
+	return requirements, nil
+}
+
+// inputSigners returns, for each of the [ins], the addresses that must sign
+// the input. Every address must be in [addrs]. The owners of each input are
+// looked up in [utxos].
+func inputSigners(
+	ins []*avax.TransferableInput,
+	utxos map[ids.ID]*avax.UTXO,
+	addrs ids.ShortSet,
+) ([][]ids.ShortID, error) {
+	requirements, err := inputRequirements(ins, utxos)
+	if err != nil {
+		return nil, err
+	}
+
+	signers := make([][]ids.ShortID, len(requirements))
+	for i, requirement := range requirements {
+		for _, addr := range requirement.signers {
+			if !addrs.Contains(addr) {
+				return nil, fmt.Errorf("input %d requires a signature from %s which the signer can't provide",
+					i,
+					addr,
+				)
+			}
+		}
+		signers[i] = requirement.signers
+	}
+	return signers, nil
+}
+
+// utxoOwners returns the owners that must authorize spending [utxo].
+func utxoOwners(utxo *avax.UTXO) (*secp256k1fx.OutputOwners, error) {
+	out := utxo.Out
+	if lockedOut, ok := out.(*platformvm.StakeableLockOut); ok {
+		out = lockedOut.TransferableOut
+	}
+	switch out := out.(type) {
+	case *secp256k1fx.TransferOutput:
+		return &out.OutputOwners, nil
+	case *secp256k1fx.MintOutput:
+		return &out.OutputOwners, nil
+	default:
+		return nil, fmt.Errorf("unexpected output type %T", out)
+	}
+}
+
+// secpInput returns the signature indices that authorize [in].
+func secpInput(in avax.TransferableIn) (*secp256k1fx.Input, error) {
+	if lockedIn, ok := in.(*platformvm.StakeableLockIn); ok {
+		in = lockedIn.TransferableIn
+	}
+	transferIn, ok := in.(*secp256k1fx.TransferInput)
+	if !ok {
+		return nil, fmt.Errorf("unexpected input type %T", in)
+	}
+	return &transferIn.Input, nil
+}
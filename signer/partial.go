@@ -0,0 +1,287 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package signer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+var (
+	errMismatchedSignatures = errors.New("number of signatures doesn't match the number of signers")
+	errMismatchedInputs     = errors.New("partial transaction doesn't match the inputs of its unsigned transaction")
+	errMissingSignatures    = errors.New("partial transaction is missing signatures")
+	errWrongSigner          = errors.New("signature wasn't produced by the input's signer")
+)
+
+// PartialTx is an unsigned transaction along with the signatures that have
+// been collected for it so far. Partial transaction files contain one JSON
+// encoded PartialTx per line.
+type PartialTx struct {
+	// Chain is the chain that the transaction will be issued to
+	Chain txs.Chain `json:"chain"`
+	// UnsignedTx is the checksummed hex encoding of the unsigned transaction
+	UnsignedTx string `json:"unsignedTx"`
+	// Inputs holds the signatures of each input, in the order that their
+	// credentials must be provided
+	Inputs []PartialInput `json:"inputs"`
+}
+
+// PartialInput tracks the signatures of a single input.
+type PartialInput struct {
+	// Threshold is the number of signatures the spent UTXO's owners require
+	Threshold uint32 `json:"threshold"`
+	// Signers are the addresses that must sign this input, in the order that
+	// their signatures must appear in the credential
+	Signers []ids.ShortID `json:"signers"`
+	// Signatures are the checksummed hex encodings of the signatures collected
+	// so far. The empty string marks a signature that is still missing.
+	Signatures []string `json:"signatures"`
+}
+
+// CreatePartial converts every unsigned transaction in [inFilePath] into a
+// partially signed transaction, with no signatures, and writes them to
+// [outFilePath]. The UTXOs consumed by the transactions are read from
+// [utxosFilePath] and are used to determine the required signers. The output
+// encoding of [opts] is ignored.
+func CreatePartial(inFilePath, outFilePath, utxosFilePath string, opts Options) error {
+	utxos, err := ReadUTXOs(utxosFilePath)
+	if err != nil {
+		return err
+	}
+
+	return txio.Transform(inFilePath, outFilePath, opts.InputEncoding, txio.Text, func(unsignedTxBytes []byte) ([]byte, error) {
+		unsignedTx, err := txs.Parse(opts.Chain, unsignedTxBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		ins, err := unsignedTx.Inputs()
+		if err != nil {
+			return nil, err
+		}
+
+		requirements, err := inputRequirements(ins, utxos)
+		if err != nil {
+			return nil, err
+		}
+
+		checkedTxHex, err := formatting.EncodeWithChecksum(formatting.Hex, unsignedTxBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		partialTx := PartialTx{
+			Chain:      unsignedTx.Chain(),
+			UnsignedTx: checkedTxHex,
+			Inputs:     make([]PartialInput, len(requirements)),
+		}
+		for i, requirement := range requirements {
+			partialTx.Inputs[i] = PartialInput{
+				Threshold:  requirement.threshold,
+				Signers:    requirement.signers,
+				Signatures: make([]string, len(requirement.signers)),
+			}
+		}
+		return marshalPartialTx(&partialTx)
+	})
+}
+
+// SignPartial adds a signature from every address of [s] that is a
+// required, but still missing, signer of the partially signed transactions in
+// [inFilePath]. The updated partially signed transactions are written to
+// [outFilePath]. Only the policy and the audit log of [opts] are used. An audit
+// entry is appended for every transaction that [s] adds signatures to.
+func SignPartial(inFilePath, outFilePath string, s Signer, opts Options) error {
+	addrs := AddressSet(s)
+	return txio.Transform(inFilePath, outFilePath, txio.Text, txio.Text, func(line []byte) ([]byte, error) {
+		partialTx, unsignedTx, err := parseCheckedPartialTx(line)
+		if err != nil {
+			return nil, err
+		}
+
+		summary, err := Review(unsignedTx, addrs, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		// missing[i] are the indices, into the signers of input i, of the
+		// signatures that [s] can add
+		missing := make([][]int, len(partialTx.Inputs))
+		signers := make([][]ids.ShortID, len(partialTx.Inputs))
+		numMissing := 0
+		for i, input := range partialTx.Inputs {
+			for j, addr := range input.Signers {
+				if input.Signatures[j] == "" && addrs.Contains(addr) {
+					missing[i] = append(missing[i], j)
+					signers[i] = append(signers[i], addr)
+					numMissing++
+				}
+			}
+		}
+
+		sigs, err := signatures(s, unsignedTx.Bytes(), signers, false)
+		if err != nil {
+			return nil, err
+		}
+		for i, input := range partialTx.Inputs {
+			for k, j := range missing[i] {
+				input.Signatures[j], err = formatting.EncodeWithChecksum(formatting.Hex, sigs[i][k])
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if opts.AuditLog != nil && numMissing > 0 {
+			if err := LogSigned(opts.AuditLog, unsignedTx, summary, nil, signers); err != nil {
+				return nil, err
+			}
+		}
+		return marshalPartialTx(partialTx)
+	})
+}
+
+// FinalizePartial verifies that every partially signed transaction in
+// [inFilePath] has collected all of its required signatures and writes the
+// signed transactions to [outFilePath]. Only the output encoding of [opts] is
+// used.
+func FinalizePartial(inFilePath, outFilePath string, opts Options) error {
+	secp := crypto.FactorySECP256K1R{}
+	return txio.Transform(inFilePath, outFilePath, txio.Text, opts.outputEncoding(), func(line []byte) ([]byte, error) {
+		partialTx, unsignedTx, err := parseCheckedPartialTx(line)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := hashing.ComputeHash256(unsignedTx.Bytes())
+		creds := make([]*secp256k1fx.Credential, len(partialTx.Inputs))
+		for i, input := range partialTx.Inputs {
+			numSigned := 0
+			for _, sigStr := range input.Signatures {
+				if sigStr != "" {
+					numSigned++
+				}
+			}
+			if numSigned < len(input.Signers) || uint32(numSigned) < input.Threshold {
+				return nil, fmt.Errorf("%w: input %d has %d of %d required signatures with a threshold of %d",
+					errMissingSignatures,
+					i,
+					numSigned,
+					len(input.Signers),
+					input.Threshold,
+				)
+			}
+
+			cred := &secp256k1fx.Credential{
+				Sigs: make([][crypto.SECP256K1RSigLen]byte, len(input.Signatures)),
+			}
+			for j, sigStr := range input.Signatures {
+				sig, err := formatting.Decode(formatting.Hex, sigStr)
+				if err != nil {
+					return nil, fmt.Errorf("input %d signature %d: %w", i, j, err)
+				}
+				if len(sig) != crypto.SECP256K1RSigLen {
+					return nil, fmt.Errorf("input %d signature %d has length %d", i, j, len(sig))
+				}
+
+				pk, err := secp.RecoverHashPublicKey(hash, sig)
+				if err != nil {
+					return nil, fmt.Errorf("input %d signature %d: %w", i, j, err)
+				}
+				if signer := pk.Address(); signer != input.Signers[j] {
+					return nil, fmt.Errorf("%w: input %d signature %d was signed by %s rather than %s",
+						errWrongSigner,
+						i,
+						j,
+						signer,
+						input.Signers[j],
+					)
+				}
+				copy(cred.Sigs[j][:], sig)
+			}
+			creds[i] = cred
+		}
+
+		return unsignedTx.Attach(creds)
+	})
+}
+
+// parsePartialTx parses a line of a partial transaction file and returns the
+// partial transaction along with the unsigned transaction's bytes.
+func parsePartialTx(line []byte) (*PartialTx, []byte, error) {
+	partialTx := &PartialTx{}
+	if err := json.Unmarshal(line, partialTx); err != nil {
+		return nil, nil, err
+	}
+
+	unsignedTxBytes, err := formatting.Decode(formatting.Hex, partialTx.UnsignedTx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, input := range partialTx.Inputs {
+		if len(input.Signers) != len(input.Signatures) {
+			return nil, nil, fmt.Errorf("input %d: %w", i, errMismatchedSignatures)
+		}
+	}
+	return partialTx, unsignedTxBytes, nil
+}
+
+// parseCheckedPartialTx parses a line of a partial transaction file and returns
+// the partial transaction along with its parsed unsigned transaction. Every
+// input of the unsigned transaction must be described by the partial
+// transaction, with one signer per signature index of the input.
+func parseCheckedPartialTx(line []byte) (*PartialTx, txs.UnsignedTx, error) {
+	partialTx, unsignedTxBytes, err := parsePartialTx(line)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unsignedTx, err := txs.Parse(partialTx.Chain, unsignedTxBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ins, err := unsignedTx.Inputs()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ins) != len(partialTx.Inputs) {
+		return nil, nil, fmt.Errorf("%w: transaction has %d inputs but %d are described",
+			errMismatchedInputs,
+			len(ins),
+			len(partialTx.Inputs),
+		)
+	}
+	for i, in := range ins {
+		input, err := secpInput(in.In)
+		if err != nil {
+			return nil, nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		if numSigners := len(partialTx.Inputs[i].Signers); len(input.SigIndices) != numSigners {
+			return nil, nil, fmt.Errorf("%w: input %d requires %d signatures but %d signers are described",
+				errMismatchedInputs,
+				i,
+				len(input.SigIndices),
+				numSigners,
+			)
+		}
+	}
+	return partialTx, unsignedTx, nil
+}
+
+func marshalPartialTx(partialTx *PartialTx) ([]byte, error) {
+	return json.Marshal(partialTx)
+}
@@ -0,0 +1,146 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package signer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+func newTestKey(t *testing.T) *crypto.PrivateKeySECP256K1R {
+	t.Helper()
+
+	secp := crypto.FactorySECP256K1R{}
+	skIntf, err := secp.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return skIntf.(*crypto.PrivateKeySECP256K1R)
+}
+
+// newTestTx returns an X-chain transaction with a single input that requires
+// one signature
+func newTestTx(t *testing.T) txs.UnsignedTx {
+	t.Helper()
+
+	assetID := ids.GenerateTestID()
+	tx, err := txs.NewAVMTx(&avm.BaseTx{BaseTx: avax.BaseTx{
+		NetworkID:    5,
+		BlockchainID: ids.GenerateTestID(),
+		Ins: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: assetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   1000,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestFinalizePartial(t *testing.T) {
+	signerKey := newTestKey(t)
+	otherKey := newTestKey(t)
+	signerAddr := signerKey.PublicKey().Address()
+
+	tests := []struct {
+		name        string
+		signers     []ids.ShortID
+		signWith    []*crypto.PrivateKeySECP256K1R
+		expectedErr error
+	}{
+		{
+			name:     "signed by the signer",
+			signers:  []ids.ShortID{signerAddr},
+			signWith: []*crypto.PrivateKeySECP256K1R{signerKey},
+		},
+		{
+			name:        "signed by another key",
+			signers:     []ids.ShortID{signerAddr},
+			signWith:    []*crypto.PrivateKeySECP256K1R{otherKey},
+			expectedErr: errWrongSigner,
+		},
+		{
+			name:        "missing signature",
+			signers:     []ids.ShortID{signerAddr},
+			signWith:    []*crypto.PrivateKeySECP256K1R{nil},
+			expectedErr: errMissingSignatures,
+		},
+		{
+			name:        "more signers than the input requires",
+			signers:     []ids.ShortID{signerAddr, otherKey.PublicKey().Address()},
+			signWith:    []*crypto.PrivateKeySECP256K1R{signerKey, otherKey},
+			expectedErr: errMismatchedInputs,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := newTestTx(t)
+			checkedTxHex, err := formatting.EncodeWithChecksum(formatting.Hex, tx.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hash := hashing.ComputeHash256(tx.Bytes())
+			input := PartialInput{
+				Threshold:  1,
+				Signers:    test.signers,
+				Signatures: make([]string, len(test.signWith)),
+			}
+			for i, key := range test.signWith {
+				if key == nil {
+					continue
+				}
+				sig, err := key.SignHash(hash)
+				if err != nil {
+					t.Fatal(err)
+				}
+				input.Signatures[i], err = formatting.EncodeWithChecksum(formatting.Hex, sig)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			line, err := marshalPartialTx(&PartialTx{
+				Chain:      tx.Chain(),
+				UnsignedTx: checkedTxHex,
+				Inputs:     []PartialInput{input},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			inPath := filepath.Join(dir, "partial")
+			outPath := filepath.Join(dir, "signed")
+			if err := os.WriteFile(inPath, append(line, '\n'), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			err = FinalizePartial(inPath, outPath, Options{OutputEncoding: txio.CheckedHex})
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected %v, got %v", test.expectedErr, err)
+			}
+			if _, statErr := os.Stat(outPath); (err == nil) != (statErr == nil) {
+				t.Fatalf("expected the signed file to be written only on success, got %v", statErr)
+			}
+		})
+	}
+}
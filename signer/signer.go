@@ -4,82 +4,144 @@
 package signer
 
 import (
-	"bufio"
-	"encoding/hex"
 	"fmt"
-	"os"
 
-	"github.com/ava-labs/avalanchego/utils/crypto"
-	"github.com/ava-labs/avalanchego/utils/formatting"
-	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+
+	"github.com/StephenButtolph/avalanche-tooling/audit"
+	"github.com/StephenButtolph/avalanche-tooling/inspect"
+	"github.com/StephenButtolph/avalanche-tooling/policy"
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
 )
 
-func Sign(inFilePath, outFilePath, secretKey string) error {
-	secretKeyBytes, err := formatting.Decode(formatting.CB58, secretKey)
-	if err != nil {
-		return err
+// Options configures how transaction files are read and written
+type Options struct {
+	// Chain that the transactions target. If txs.Unknown, the chain of each
+	// transaction is detected.
+	Chain txs.Chain
+	// InputEncoding of the transaction file. If txio.Auto, the encoding is
+	// detected.
+	InputEncoding txio.Encoding
+	// OutputEncoding of the signed transactions. If txio.Auto, the
+	// transactions are written as txio.CheckedHex.
+	OutputEncoding txio.Encoding
+	// AuditLog, if non-nil, has an entry appended for every transaction
+	// signed by Sign or SignPartial. Entries are appended as each transaction
+	// is signed, so they are recorded even if a later transaction fails to
+	// sign.
+	AuditLog *audit.Log
+	// Policy, if non-nil, must allow every transaction before it is signed.
+	// It is enforced by Sign and SignPartial.
+	Policy *policy.Policy
+}
+
+func (o Options) outputEncoding() txio.Encoding {
+	if o.OutputEncoding == txio.Auto {
+		return txio.CheckedHex
 	}
+	return o.OutputEncoding
+}
 
-	secp := crypto.FactorySECP256K1R{}
-	skIntf, err := secp.ToPrivateKey(secretKeyBytes)
+// Sign signs every unsigned transaction in [inFilePath] and writes the signed
+// transactions to [outFilePath]. The UTXOs consumed by the transactions are
+// read from [utxosFilePath] and are used to determine which addresses of [s]
+// must sign each input.
+func Sign(inFilePath, outFilePath, utxosFilePath string, s Signer, opts Options) error {
+	utxos, err := ReadUTXOs(utxosFilePath)
 	if err != nil {
 		return err
 	}
-	sk := skIntf.(*crypto.PrivateKeySECP256K1R)
 
-	inFile, err := os.Open(inFilePath)
+	addrs := AddressSet(s)
+	return txio.Transform(inFilePath, outFilePath, opts.InputEncoding, opts.outputEncoding(), func(unsignedTxBytes []byte) ([]byte, error) {
+		return signTx(unsignedTxBytes, utxos, s, addrs, opts)
+	})
+}
+
+func signTx(
+	unsignedTxBytes []byte,
+	utxos map[ids.ID]*avax.UTXO,
+	s Signer,
+	addrs ids.ShortSet,
+	opts Options,
+) ([]byte, error) {
+	unsignedTx, err := txs.Parse(opts.Chain, unsignedTxBytes)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer inFile.Close()
 
-	outFile, err := os.Create(outFilePath)
+	ins, err := unsignedTx.Inputs()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer outFile.Close()
-
-	scanner := bufio.NewScanner(inFile)
-	for scanner.Scan() {
-		unsignedTxHex := scanner.Text()
-		unsignedTxBytes, err := hex.DecodeString(unsignedTxHex)
-		if err != nil {
-			return err
-		}
 
-		var unsignedTx platformvm.UnsignedTx
-		version, err := platformvm.Codec.Unmarshal(unsignedTxBytes, &unsignedTx)
-		if err != nil {
-			return err
-		}
-		if version != 0 {
-			return fmt.Errorf("expected codec version 0 but got %d", version)
-		}
+	signers, err := inputSigners(ins, utxos, addrs)
+	if err != nil {
+		return nil, err
+	}
 
-		tx := platformvm.Tx{
-			UnsignedTx: unsignedTx,
-		}
-		err = tx.Sign(platformvm.Codec, [][]*crypto.PrivateKeySECP256K1R{{sk}})
-		if err != nil {
-			return err
-		}
+	summary, err := Review(unsignedTx, addrs, opts)
+	if err != nil {
+		return nil, err
+	}
 
-		signedTxBytes := tx.Bytes()
-		signedTxHex, err := formatting.EncodeWithChecksum(formatting.Hex, signedTxBytes)
-		if err != nil {
-			return err
-		}
+	signedTxBytes, err := SignTx(unsignedTx, s, signers)
+	if err != nil || opts.AuditLog == nil {
+		return signedTxBytes, err
+	}
+	return signedTxBytes, LogSigned(opts.AuditLog, unsignedTx, summary, signedTxBytes, signers)
+}
 
-		_, err = outFile.WriteString(signedTxHex)
-		if err != nil {
-			return err
+// Review checks [unsignedTx], to be signed by [addrs], against the policy of
+// [opts]. The transaction's summary is returned if the policy or the audit log
+// of [opts] needs it, and nil otherwise.
+func Review(unsignedTx txs.UnsignedTx, addrs ids.ShortSet, opts Options) (*inspect.Summary, error) {
+	if opts.Policy == nil && opts.AuditLog == nil {
+		return nil, nil
+	}
+	summary, err := inspect.Describe(unsignedTx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't describe transaction: %w", err)
+	}
+	if opts.Policy != nil {
+		if err := opts.Policy.Check(summary, addrs); err != nil {
+			return nil, err
 		}
+	}
+	return summary, nil
+}
 
-		_, err = outFile.WriteString("\n")
-		if err != nil {
-			return err
+// LogSigned appends the signing of [unsignedTx] by [signers] to [auditLog].
+// If the signatures don't complete the transaction, [signedTxBytes] is nil.
+func LogSigned(
+	auditLog *audit.Log,
+	unsignedTx txs.UnsignedTx,
+	summary *inspect.Summary,
+	signedTxBytes []byte,
+	signers [][]ids.ShortID,
+) error {
+	addrs := ids.ShortSet{}
+	signingAddrs := []ids.ShortID(nil)
+	for _, inputSigners := range signers {
+		for _, addr := range inputSigners {
+			if !addrs.Contains(addr) {
+				addrs.Add(addr)
+				signingAddrs = append(signingAddrs, addr)
+			}
 		}
 	}
 
-	return scanner.Err()
+	var signedTxID ids.ID
+	if signedTxBytes != nil {
+		signedTxID = hashing.ComputeHash256Array(signedTxBytes)
+	}
+	return auditLog.Append(audit.Entry{
+		UnsignedTxID: hashing.ComputeHash256Array(unsignedTx.Bytes()),
+		SignedTxID:   signedTxID,
+		Signers:      signingAddrs,
+		Summary:      summary,
+	})
 }
@@ -0,0 +1,44 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package signer
+
+import (
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+
+	"github.com/StephenButtolph/avalanche-tooling/txio"
+	"github.com/StephenButtolph/avalanche-tooling/txs"
+)
+
+// ReadUTXOs parses a file of UTXOs, as returned by the getUTXOs APIs of either
+// the P-chain or the X-chain. The encoding of the file is detected. The
+// returned map is keyed by the ID that an input spending the UTXO would
+// reference.
+func ReadUTXOs(filePath string) (map[ids.ID]*avax.UTXO, error) {
+	utxos := make(map[ids.ID]*avax.UTXO)
+	err := txio.ForEach(filePath, txio.Auto, func(utxoBytes []byte) error {
+		utxo, err := parseUTXO(utxoBytes)
+		if err != nil {
+			return err
+		}
+		utxos[utxo.InputID()] = utxo
+		return nil
+	})
+	return utxos, err
+}
+
+// parseUTXO decodes a UTXO with the P-chain codec, which understands stakeable
+// locked outputs, falling back to the X-chain codec, which understands NFT and
+// property outputs.
+func parseUTXO(utxoBytes []byte) (*avax.UTXO, error) {
+	utxo := &avax.UTXO{}
+	if _, err := platformvm.Codec.Unmarshal(utxoBytes, utxo); err == nil {
+		return utxo, nil
+	}
+
+	utxo = &avax.UTXO{}
+	_, err := txs.XCodec.Unmarshal(utxoBytes, utxo)
+	return utxo, err
+}
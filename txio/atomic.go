@@ -0,0 +1,88 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txio
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// AtomicFile is written to a temporary file in the same directory as its
+// destination. The destination is only replaced once the file is committed,
+// so a failure never leaves a partially written destination behind.
+type AtomicFile struct {
+	*os.File
+	path string
+	done bool
+}
+
+// CreateAtomic creates a temporary file that will replace [path] once
+// committed. Like a file created by os.Create, it may be read by anyone.
+func CreateAtomic(path string) (*AtomicFile, error) {
+	return createAtomic(path, 0o644)
+}
+
+// CreatePrivateAtomic creates a temporary file, that only its owner may read,
+// that will replace [path] once committed
+func CreatePrivateAtomic(path string) (*AtomicFile, error) {
+	return createAtomic(path, 0o600)
+}
+
+func createAtomic(path string, perm os.FileMode) (*AtomicFile, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	file, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(perm); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+	return &AtomicFile{
+		File: file,
+		path: path,
+	}, nil
+}
+
+// Commit flushes the temporary file to disk and renames it to the
+// destination.
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return os.ErrClosed
+	}
+	f.done = true
+
+	if err := f.File.Sync(); err != nil {
+		f.remove()
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		_ = os.Remove(f.File.Name())
+		return err
+	}
+	if err := os.Rename(f.File.Name(), f.path); err != nil {
+		_ = os.Remove(f.File.Name())
+		return err
+	}
+	return nil
+}
+
+// Abort removes the temporary file. It is a no-op if the file was already
+// committed or aborted, so it is safe to defer.
+func (f *AtomicFile) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.remove()
+}
+
+func (f *AtomicFile) remove() {
+	_ = f.File.Close()
+	_ = os.Remove(f.File.Name())
+}
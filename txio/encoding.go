@@ -0,0 +1,176 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txio
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/utils/formatting"
+)
+
+// checksumLen is the length of the checksum that ends CheckedHex and CB58
+// records
+const checksumLen = 4
+
+var (
+	errUnknownEncoding     = errors.New("unknown encoding")
+	errUnrecognizedRecord  = errors.New("couldn't detect the encoding of the record")
+	errAutoNotWritable     = errors.New("an explicit encoding is required to write records")
+	errBinaryNotLineBased  = errors.New("binary records aren't line based")
+	errHexMissingPrefix    = errors.New("missing 0x prefix")
+	errHexUnexpectedPrefix = errors.New("unexpected 0x prefix")
+)
+
+// Encoding describes how records, typically transactions, are stored in a
+// file.
+type Encoding uint8
+
+const (
+	// Auto detects the encoding of a file while reading it. Binary files are
+	// detected once per file and text encodings are detected once per line.
+	Auto Encoding = iota
+	// RawHex is one hex string, without a prefix or checksum, per line
+	RawHex
+	// Hex is one 0x prefixed hex string, without a checksum, per line
+	Hex
+	// CheckedHex is one 0x prefixed hex string, with a checksum, per line.
+	// This is the hex encoding used by the avalanchego APIs.
+	CheckedHex
+	// CB58 is one CB58 string, with a checksum, per line
+	CB58
+	// Binary is the raw bytes of each record prefixed by their length as a
+	// 4 byte big-endian integer
+	Binary
+	// Text is one line of text per record. It is used for files, such as
+	// partially signed transactions, that aren't encoded transactions and is
+	// never detected automatically.
+	Text
+)
+
+var encodingNames = map[Encoding]string{
+	Auto:       "auto",
+	RawHex:     "raw-hex",
+	Hex:        "hex",
+	CheckedHex: "checked-hex",
+	CB58:       "cb58",
+	Binary:     "binary",
+	Text:       "text",
+}
+
+func (e Encoding) String() string {
+	if name, ok := encodingNames[e]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseEncoding parses the name of an encoding, such as "checked-hex".
+func ParseEncoding(name string) (Encoding, error) {
+	name = strings.ToLower(name)
+	for e, encodingName := range encodingNames {
+		if name == encodingName {
+			return e, nil
+		}
+	}
+	return Auto, fmt.Errorf("%w: %q", errUnknownEncoding, name)
+}
+
+// Names returns the names of the encodings that can be selected by users.
+func Names() []string {
+	return []string{
+		Auto.String(),
+		RawHex.String(),
+		Hex.String(),
+		CheckedHex.String(),
+		CB58.String(),
+		Binary.String(),
+	}
+}
+
+// Decode parses a single line encoded with [e]. If [e] is Auto, the encoding
+// of the line is detected and returned.
+func (e Encoding) Decode(line string) ([]byte, Encoding, error) {
+	switch e {
+	case Auto:
+		return decodeAuto(line)
+	case RawHex:
+		if strings.HasPrefix(line, "0x") {
+			return nil, e, errHexUnexpectedPrefix
+		}
+		b, err := hex.DecodeString(line)
+		return b, e, err
+	case Hex:
+		if !strings.HasPrefix(line, "0x") {
+			return nil, e, errHexMissingPrefix
+		}
+		b, err := hex.DecodeString(line[2:])
+		return b, e, err
+	case CheckedHex:
+		b, err := formatting.Decode(formatting.Hex, line)
+		return b, e, err
+	case CB58:
+		b, err := formatting.Decode(formatting.CB58, line)
+		return b, e, err
+	case Text:
+		return []byte(line), e, nil
+	case Binary:
+		return nil, e, errBinaryNotLineBased
+	default:
+		return nil, e, errUnknownEncoding
+	}
+}
+
+// Encode formats [b] as a single line, without the trailing newline.
+func (e Encoding) Encode(b []byte) (string, error) {
+	switch e {
+	case RawHex:
+		return hex.EncodeToString(b), nil
+	case Hex:
+		return "0x" + hex.EncodeToString(b), nil
+	case CheckedHex:
+		return formatting.EncodeWithChecksum(formatting.Hex, b)
+	case CB58:
+		return formatting.EncodeWithChecksum(formatting.CB58, b)
+	case Text:
+		return string(b), nil
+	case Auto:
+		return "", errAutoNotWritable
+	case Binary:
+		return "", errBinaryNotLineBased
+	default:
+		return "", errUnknownEncoding
+	}
+}
+
+// decodeAuto detects the text encoding of [line]. A 0x prefixed line that is
+// long enough to end in a checksum is treated as CheckedHex, and its checksum
+// must be valid. Shorter 0x prefixed lines are treated as Hex. An unprefixed
+// line is treated as RawHex if it is valid hex and as CB58 otherwise.
+func decodeAuto(line string) ([]byte, Encoding, error) {
+	if strings.HasPrefix(line, "0x") {
+		b, err := hex.DecodeString(line[2:])
+		if err != nil {
+			return nil, Auto, err
+		}
+		if len(b) < checksumLen {
+			return b, Hex, nil
+		}
+		b, err = formatting.Decode(formatting.Hex, line)
+		if err != nil {
+			return nil, CheckedHex, fmt.Errorf("%w, use the %s encoding for hex without a checksum", err, Hex)
+		}
+		return b, CheckedHex, nil
+	}
+
+	if b, err := hex.DecodeString(line); err == nil {
+		return b, RawHex, nil
+	}
+	if b, err := formatting.Decode(formatting.CB58, line); err == nil {
+		return b, CB58, nil
+	}
+	return nil, Auto, errUnrecognizedRecord
+}
@@ -0,0 +1,246 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Transform applies [transform] to every record of [inFilePath], which is
+// encoded with [inEncoding], and writes the results to [outFilePath] encoded
+// with [outEncoding]. The output is written atomically, so [outFilePath] is
+// left untouched if an error occurs.
+func Transform(
+	inFilePath string,
+	outFilePath string,
+	inEncoding Encoding,
+	outEncoding Encoding,
+	transform func(record []byte) ([]byte, error),
+) error {
+	return TransformParallel(inFilePath, outFilePath, inEncoding, outEncoding, 1, transform)
+}
+
+// TransformParallel is Transform with up to [workers] records being decoded,
+// transformed, and encoded concurrently. The output is written in the same
+// order as the input. At most 2*[workers] records are held in memory at once.
+// [transform] must be safe to call concurrently if [workers] > 1.
+func TransformParallel(
+	inFilePath string,
+	outFilePath string,
+	inEncoding Encoding,
+	outEncoding Encoding,
+	workers int,
+	transform func(record []byte) ([]byte, error),
+) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	inFile, err := os.Open(inFilePath)
+	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+
+	outFile, err := CreateAtomic(outFilePath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Abort()
+
+	reader := NewReader(inFile, inEncoding)
+	writer := NewWriter(outFile, outEncoding)
+	process := func(raw []byte, position string) ([]byte, error) {
+		record, err := reader.decode(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", position, err)
+		}
+		result, err := transform(record)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", position, err)
+		}
+		return writer.encode(result)
+	}
+
+	if workers == 1 {
+		err = transformSequential(reader, writer, process)
+	} else {
+		err = transformConcurrent(reader, writer, workers, process)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return outFile.Commit()
+}
+
+func transformSequential(
+	reader *Reader,
+	writer *Writer,
+	process func(raw []byte, position string) ([]byte, error),
+) error {
+	for {
+		raw, err := reader.readRaw()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		encoded, err := process(raw, reader.Position())
+		if err != nil {
+			return err
+		}
+
+		if err := writer.writeEncoded(encoded); err != nil {
+			return err
+		}
+	}
+}
+
+type job struct {
+	index    int
+	raw      []byte
+	position string
+}
+
+type result struct {
+	index   int
+	encoded []byte
+	err     error
+}
+
+func transformConcurrent(
+	reader *Reader,
+	writer *Writer,
+	workers int,
+	process func(raw []byte, position string) ([]byte, error),
+) error {
+	var (
+		jobs    = make(chan job, workers)
+		results = make(chan result, workers)
+		// inFlight bounds the number of records that have been read but not
+		// yet written, which bounds the memory held by out of order results.
+		inFlight = make(chan struct{}, 2*workers)
+		done     = make(chan struct{})
+		readErr  error
+		wg       sync.WaitGroup
+	)
+
+	// Read records and hand them out to the workers
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			select {
+			case inFlight <- struct{}{}:
+			case <-done:
+				return
+			}
+
+			raw, err := reader.readRaw()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					readErr = err
+				}
+				return
+			}
+
+			select {
+			case jobs <- job{index: index, raw: raw, position: reader.Position()}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				encoded, err := process(j.raw, j.position)
+				select {
+				case results <- result{index: j.index, encoded: encoded, err: err}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Write the results in the order they were read
+	var (
+		pending = make(map[int][]byte)
+		next    = 0
+		err     error
+	)
+	for r := range results {
+		if err != nil {
+			continue
+		}
+		if r.err != nil {
+			err = r.err
+			close(done)
+			continue
+		}
+
+		pending[r.index] = r.encoded
+		for {
+			encoded, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-inFlight
+
+			if err = writer.writeEncoded(encoded); err != nil {
+				close(done)
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	// All of the workers have exited, so the reader has finished.
+	return readErr
+}
+
+// ForEach calls [f] with every record of [inFilePath], which is encoded with
+// [inEncoding].
+func ForEach(inFilePath string, inEncoding Encoding, f func(record []byte) error) error {
+	inFile, err := os.Open(inFilePath)
+	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+
+	reader := NewReader(inFile, inEncoding)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := f(record); err != nil {
+			return fmt.Errorf("%s: %w", reader.Position(), err)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// MaxRecordSize is the largest record that will be read
+	MaxRecordSize = 500_000_000
+
+	lengthPrefixSize = 4
+)
+
+// ErrRecordTooLarge is returned when a record is larger than MaxRecordSize
+var ErrRecordTooLarge = errors.New("record is too large")
+
+// Reader reads records from a file of a single encoding.
+type Reader struct {
+	encoding Encoding
+	reader   *bufio.Reader
+	scanner  *bufio.Scanner
+	index    int
+}
+
+// NewReader returns a reader of the records in [r] encoded with [encoding].
+func NewReader(r io.Reader, encoding Encoding) *Reader {
+	return &Reader{
+		encoding: encoding,
+		reader:   bufio.NewReader(r),
+	}
+}
+
+// Index returns the number, starting from 1, of the most recently read record.
+// For text encodings this is the line number.
+func (r *Reader) Index() int { return r.index }
+
+// Position describes the location of the most recently read record, such as
+// "line 3", for use in error messages.
+func (r *Reader) Position() string {
+	if r.encoding == Binary {
+		return fmt.Sprintf("record %d", r.index)
+	}
+	return fmt.Sprintf("line %d", r.index)
+}
+
+// Read returns the next record. Empty lines are skipped. io.EOF is returned
+// once every record has been read.
+func (r *Reader) Read() ([]byte, error) {
+	raw, err := r.readRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := r.decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.Position(), err)
+	}
+	return record, nil
+}
+
+// readRaw returns the next record without decoding it. For text encodings this
+// is the trimmed line and for binary files this is the record itself.
+func (r *Reader) readRaw() ([]byte, error) {
+	if r.encoding == Auto && r.scanner == nil {
+		isBinary, err := r.detectBinary()
+		if err != nil {
+			return nil, err
+		}
+		if isBinary {
+			r.encoding = Binary
+		}
+	}
+
+	if r.encoding == Binary {
+		return r.readBinary()
+	}
+	return r.readLine()
+}
+
+// decode converts a record returned by readRaw into its bytes. It doesn't
+// modify the reader, so it may be called concurrently.
+func (r *Reader) decode(raw []byte) ([]byte, error) {
+	if r.encoding == Binary {
+		return raw, nil
+	}
+	record, _, err := r.encoding.Decode(string(raw))
+	return record, err
+}
+
+func (r *Reader) readLine() ([]byte, error) {
+	if r.scanner == nil {
+		r.scanner = bufio.NewScanner(r.reader)
+		r.scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), MaxRecordSize)
+	}
+
+	for r.scanner.Scan() {
+		r.index++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		// The scanner's buffer is reused, so the line must be copied.
+		return append([]byte(nil), line...), nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = fmt.Errorf("%w: longer than %d bytes", ErrRecordTooLarge, MaxRecordSize)
+		}
+		return nil, fmt.Errorf("line %d: %w", r.index+1, err)
+	}
+	return nil, io.EOF
+}
+
+func (r *Reader) readBinary() ([]byte, error) {
+	var lengthBytes [lengthPrefixSize]byte
+	if _, err := io.ReadFull(r.reader, lengthBytes[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("record %d: truncated length prefix", r.index+1)
+		}
+		return nil, err
+	}
+	r.index++
+
+	length := binary.BigEndian.Uint32(lengthBytes[:])
+	if length > MaxRecordSize {
+		return nil, fmt.Errorf("record %d: %w: %d bytes", r.index, ErrRecordTooLarge, length)
+	}
+
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, b); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("record %d: %w", r.index, err)
+	}
+	return b, nil
+}
+
+// detectBinary reports whether the file looks like it is length-prefixed
+// binary. Text encodings only contain printable characters, whereas the first
+// byte of a length prefix is zero for any record smaller than 16MB.
+func (r *Reader) detectBinary() (bool, error) {
+	first, err := r.reader.Peek(1)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	switch c := first[0]; {
+	case c == '\t', c == '\n', c == '\r':
+		return false, nil
+	case c < ' ', c > '~':
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Writer writes records to a file using a single encoding.
+type Writer struct {
+	encoding Encoding
+	writer   *bufio.Writer
+}
+
+// NewWriter returns a writer of records to [w] encoded with [encoding]. Flush
+// must be called once every record has been written.
+func NewWriter(w io.Writer, encoding Encoding) *Writer {
+	return &Writer{
+		encoding: encoding,
+		writer:   bufio.NewWriter(w),
+	}
+}
+
+// Write appends [b] as the next record.
+func (w *Writer) Write(b []byte) error {
+	encoded, err := w.encode(b)
+	if err != nil {
+		return err
+	}
+	return w.writeEncoded(encoded)
+}
+
+// encode returns the bytes that represent [b] in the file, including the
+// length prefix or trailing newline. It doesn't modify the writer, so it may
+// be called concurrently.
+func (w *Writer) encode(b []byte) ([]byte, error) {
+	if w.encoding == Binary {
+		if len(b) > MaxRecordSize {
+			return nil, fmt.Errorf("%w: %d bytes", ErrRecordTooLarge, len(b))
+		}
+
+		encoded := make([]byte, lengthPrefixSize+len(b))
+		binary.BigEndian.PutUint32(encoded, uint32(len(b)))
+		copy(encoded[lengthPrefixSize:], b)
+		return encoded, nil
+	}
+
+	line, err := w.encoding.Encode(b)
+	if err != nil {
+		return nil, err
+	}
+	encoded := make([]byte, len(line)+1)
+	copy(encoded, line)
+	encoded[len(line)] = '\n'
+	return encoded, nil
+}
+
+func (w *Writer) writeEncoded(encoded []byte) error {
+	_, err := w.writer.Write(encoded)
+	return err
+}
+
+// Flush writes any buffered records to the underlying writer.
+func (w *Writer) Flush() error { return w.writer.Flush() }
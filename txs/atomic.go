@@ -0,0 +1,187 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/codec"
+	"github.com/ava-labs/avalanchego/codec/linearcodec"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/components/verify"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+)
+
+// EVMAddressLen is the length of a C-chain account address
+const EVMAddressLen = 20
+
+// errEVMInputsUnsupported is returned for C-chain exports, whose inputs are
+// authorized by the keys of EVM accounts rather than of AVAX addresses
+var errEVMInputsUnsupported = errors.New("signing EVM inputs isn't supported")
+
+// CCodec is the codec used by the C-chain for atomic transactions. It matches
+// the wire format of coreth's atomic transactions, which this module doesn't
+// depend on.
+var CCodec codec.Manager
+
+func init() {
+	c := linearcodec.NewDefault()
+	CCodec = codec.NewDefaultManager()
+	errs := wrappers.Errs{}
+	errs.Add(
+		c.RegisterType(&UnsignedImportTx{}),
+		c.RegisterType(&UnsignedExportTx{}),
+	)
+	c.SkipRegistrations(3)
+	errs.Add(
+		c.RegisterType(&secp256k1fx.TransferInput{}),
+		c.RegisterType(&secp256k1fx.MintOutput{}),
+		c.RegisterType(&secp256k1fx.TransferOutput{}),
+		c.RegisterType(&secp256k1fx.MintOperation{}),
+		c.RegisterType(&secp256k1fx.Credential{}),
+		c.RegisterType(&secp256k1fx.Input{}),
+		c.RegisterType(&secp256k1fx.OutputOwners{}),
+		CCodec.RegisterCodec(CodecVersion, c),
+	)
+	if errs.Errored() {
+		panic(errs.Err)
+	}
+}
+
+// EVMOutput credits [Amount] of [AssetID] to the C-chain account [Address]
+type EVMOutput struct {
+	Address [EVMAddressLen]byte `serialize:"true" json:"address"`
+	Amount  uint64              `serialize:"true" json:"amount"`
+	AssetID ids.ID              `serialize:"true" json:"assetID"`
+}
+
+// EVMInput debits [Amount] of [AssetID] from the C-chain account [Address]
+type EVMInput struct {
+	Address [EVMAddressLen]byte `serialize:"true" json:"address"`
+	Amount  uint64              `serialize:"true" json:"amount"`
+	AssetID ids.ID              `serialize:"true" json:"assetID"`
+	Nonce   uint64              `serialize:"true" json:"nonce"`
+}
+
+// UnsignedAtomicTx is a C-chain atomic transaction
+type UnsignedAtomicTx interface {
+	atomicTx()
+}
+
+// UnsignedImportTx imports UTXOs exported from [SourceChain] into
+// C-chain accounts
+type UnsignedImportTx struct {
+	NetworkID      uint32                    `serialize:"true" json:"networkID"`
+	BlockchainID   ids.ID                    `serialize:"true" json:"blockchainID"`
+	SourceChain    ids.ID                    `serialize:"true" json:"sourceChain"`
+	ImportedInputs []*avax.TransferableInput `serialize:"true" json:"importedInputs"`
+	Outs           []EVMOutput               `serialize:"true" json:"outputs"`
+}
+
+// UnsignedExportTx exports funds from C-chain accounts to
+// [DestinationChain]
+type UnsignedExportTx struct {
+	NetworkID        uint32                     `serialize:"true" json:"networkID"`
+	BlockchainID     ids.ID                     `serialize:"true" json:"blockchainID"`
+	DestinationChain ids.ID                     `serialize:"true" json:"destinationChain"`
+	Ins              []EVMInput                 `serialize:"true" json:"inputs"`
+	ExportedOutputs  []*avax.TransferableOutput `serialize:"true" json:"exportedOutputs"`
+}
+
+func (*UnsignedImportTx) atomicTx() {}
+func (*UnsignedExportTx) atomicTx() {}
+
+// signedAtomicTx is a C-chain atomic transaction along with its credentials
+type signedAtomicTx struct {
+	UnsignedAtomicTx `serialize:"true" json:"unsignedTx"`
+	Creds            []verify.Verifiable `serialize:"true" json:"credentials"`
+}
+
+// NewAtomicTx returns the C-chain atomic transaction [tx]
+func NewAtomicTx(tx UnsignedAtomicTx) (UnsignedTx, error) {
+	unsignedTxBytes, err := CCodec.Marshal(CodecVersion, &tx)
+	if err != nil {
+		return nil, err
+	}
+	return &atomicTx{
+		tx:    tx,
+		bytes: unsignedTxBytes,
+	}, nil
+}
+
+type atomicTx struct {
+	tx    UnsignedAtomicTx
+	bytes []byte
+}
+
+func parseAtomicTx(unsignedTxBytes []byte) (*atomicTx, error) {
+	var unsignedTx UnsignedAtomicTx
+	version, err := CCodec.Unmarshal(unsignedTxBytes, &unsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	if version != CodecVersion {
+		return nil, fmt.Errorf("expected codec version %d but got %d", CodecVersion, version)
+	}
+	return &atomicTx{
+		tx:    unsignedTx,
+		bytes: unsignedTxBytes,
+	}, nil
+}
+
+func parseSignedAtomicTx(signedTxBytes []byte) (*atomicTx, error) {
+	tx := signedAtomicTx{}
+	version, err := CCodec.Unmarshal(signedTxBytes, &tx)
+	if err != nil {
+		return nil, err
+	}
+	if version != CodecVersion {
+		return nil, fmt.Errorf("expected codec version %d but got %d", CodecVersion, version)
+	}
+	unsignedTxBytes, err := CCodec.Marshal(CodecVersion, &tx.UnsignedAtomicTx)
+	if err != nil {
+		return nil, err
+	}
+	return &atomicTx{
+		tx:    tx.UnsignedAtomicTx,
+		bytes: unsignedTxBytes,
+	}, nil
+}
+
+func (*atomicTx) Chain() Chain      { return C }
+func (t *atomicTx) Bytes() []byte   { return t.bytes }
+func (t *atomicTx) Tx() interface{} { return t.tx }
+
+func (t *atomicTx) Inputs() ([]*avax.TransferableInput, error) {
+	switch tx := t.tx.(type) {
+	case *UnsignedImportTx:
+		return tx.ImportedInputs, nil
+	case *UnsignedExportTx:
+		return nil, errEVMInputsUnsupported
+	default:
+		return nil, fmt.Errorf("can't sign transaction of type %T", t.tx)
+	}
+}
+
+func (t *atomicTx) Attach(creds []*secp256k1fx.Credential) ([]byte, error) {
+	ins, err := t.Inputs()
+	if err != nil {
+		return nil, err
+	}
+	if len(ins) != len(creds) {
+		return nil, fmt.Errorf("%w: expected %d but got %d", errWrongNumCredentials, len(ins), len(creds))
+	}
+
+	tx := &signedAtomicTx{
+		UnsignedAtomicTx: t.tx,
+		Creds:            make([]verify.Verifiable, len(creds)),
+	}
+	for i, cred := range creds {
+		tx.Creds[i] = cred
+	}
+	return CCodec.Marshal(CodecVersion, tx)
+}
@@ -0,0 +1,111 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+)
+
+func TestAtomicTxs(t *testing.T) {
+	assetID := ids.GenerateTestID()
+	importTx := &UnsignedImportTx{
+		NetworkID:    5,
+		BlockchainID: ids.GenerateTestID(),
+		SourceChain:  ids.GenerateTestID(),
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: assetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   2000,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+		Outs: []EVMOutput{{
+			Address: [EVMAddressLen]byte{1},
+			Amount:  1000,
+			AssetID: assetID,
+		}},
+	}
+	exportTx := &UnsignedExportTx{
+		NetworkID:        5,
+		BlockchainID:     ids.GenerateTestID(),
+		DestinationChain: ids.GenerateTestID(),
+		Ins: []EVMInput{{
+			Address: [EVMAddressLen]byte{1},
+			Amount:  2000,
+			AssetID: assetID,
+		}},
+	}
+
+	tests := []struct {
+		name        string
+		tx          UnsignedAtomicTx
+		numInputs   int
+		expectedErr error
+	}{
+		{
+			name:      "import",
+			tx:        importTx,
+			numInputs: 1,
+		},
+		{
+			name:        "export",
+			tx:          exportTx,
+			expectedErr: errEVMInputsUnsupported,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx, err := NewAtomicTx(test.tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := Parse(Unknown, tx.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Chain() != C {
+				t.Fatalf("expected the C-chain to be detected, got %s", parsed.Chain())
+			}
+
+			ins, err := parsed.Inputs()
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected %v, got %v", test.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(ins) != test.numInputs {
+				t.Fatalf("expected %d inputs, got %d", test.numInputs, len(ins))
+			}
+
+			if _, err := parsed.Attach(nil); !errors.Is(err, errWrongNumCredentials) {
+				t.Fatalf("expected %v, got %v", errWrongNumCredentials, err)
+			}
+			creds := make([]*secp256k1fx.Credential, len(ins))
+			for i := range creds {
+				creds[i] = &secp256k1fx.Credential{}
+			}
+			signedTxBytes, err := parsed.Attach(creds)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signed, err := ParseSigned(Unknown, signedTxBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signed.Chain() != C || !bytes.Equal(signed.Bytes(), tx.Bytes()) {
+				t.Fatalf("expected the signed C-chain transaction to contain the unsigned transaction")
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errUnknownChain = errors.New("unknown chain")
+
+// Chain identifies which primary network chain a transaction targets
+type Chain uint8
+
+const (
+	// Unknown means the chain should be detected from the transaction bytes
+	Unknown Chain = iota
+	P
+	X
+	// C is only used for the C-chain's atomic transactions
+	C
+)
+
+func (c Chain) String() string {
+	switch c {
+	case P:
+		return "P"
+	case X:
+		return "X"
+	case C:
+		return "C"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseChain parses a chain alias such as "P" or "x". The empty string,
+// "auto", and "unknown" are parsed as Unknown.
+func ParseChain(alias string) (Chain, error) {
+	switch strings.ToUpper(alias) {
+	case "", "AUTO", "UNKNOWN":
+		return Unknown, nil
+	case "P":
+		return P, nil
+	case "X":
+		return X, nil
+	case "C":
+		return C, nil
+	default:
+		return Unknown, fmt.Errorf("%w: %q", errUnknownChain, alias)
+	}
+}
+
+func (c Chain) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *Chain) UnmarshalText(text []byte) error {
+	chain, err := ParseChain(string(text))
+	*c = chain
+	return err
+}
@@ -0,0 +1,30 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txs
+
+import (
+	"github.com/ava-labs/avalanchego/codec"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/nftfx"
+	"github.com/ava-labs/avalanchego/vms/propertyfx"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+)
+
+// CodecVersion is the only codec version used by the supported chains
+const CodecVersion = 0
+
+// XCodec is the codec used by the X-chain for transactions and UTXOs.
+var XCodec codec.Manager
+
+func init() {
+	gc, _, err := avm.NewCodecs([]avm.Fx{
+		&secp256k1fx.Fx{},
+		&nftfx.Fx{},
+		&propertyfx.Fx{},
+	})
+	if err != nil {
+		panic(err)
+	}
+	XCodec = gc
+}
@@ -0,0 +1,79 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txs
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+)
+
+// ParseSigned decodes [signedTxBytes] as a signed transaction of [chain] and
+// returns the transaction without its credentials. If [chain] is Unknown, the
+// chain is detected from the transaction's type.
+func ParseSigned(chain Chain, signedTxBytes []byte) (UnsignedTx, error) {
+	switch chain {
+	case P:
+		return parseSignedPlatformTx(signedTxBytes)
+	case X:
+		return parseSignedAVMTx(signedTxBytes)
+	case C:
+		return parseSignedAtomicTx(signedTxBytes)
+	case Unknown:
+		pTx, pErr := parseSignedPlatformTx(signedTxBytes)
+		if pErr == nil {
+			return pTx, nil
+		}
+		xTx, xErr := parseSignedAVMTx(signedTxBytes)
+		if xErr == nil {
+			return xTx, nil
+		}
+		cTx, cErr := parseSignedAtomicTx(signedTxBytes)
+		if cErr == nil {
+			return cTx, nil
+		}
+		return nil, fmt.Errorf("couldn't parse as a signed P-chain (%s), X-chain (%s), or C-chain (%s) transaction", pErr, xErr, cErr)
+	default:
+		return nil, errUnknownChain
+	}
+}
+
+func parseSignedPlatformTx(signedTxBytes []byte) (*platformTx, error) {
+	tx := platformvm.Tx{}
+	version, err := platformvm.Codec.Unmarshal(signedTxBytes, &tx)
+	if err != nil {
+		return nil, err
+	}
+	if version != CodecVersion {
+		return nil, fmt.Errorf("expected codec version %d but got %d", CodecVersion, version)
+	}
+	unsignedTxBytes, err := platformvm.Codec.Marshal(CodecVersion, &tx.UnsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	return &platformTx{
+		tx:    tx.UnsignedTx,
+		bytes: unsignedTxBytes,
+	}, nil
+}
+
+func parseSignedAVMTx(signedTxBytes []byte) (*avmTx, error) {
+	tx := avm.Tx{}
+	version, err := XCodec.Unmarshal(signedTxBytes, &tx)
+	if err != nil {
+		return nil, err
+	}
+	if version != CodecVersion {
+		return nil, fmt.Errorf("expected codec version %d but got %d", CodecVersion, version)
+	}
+	unsignedTxBytes, err := XCodec.Marshal(CodecVersion, &tx.UnsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	return &avmTx{
+		tx:    tx.UnsignedTx,
+		bytes: unsignedTxBytes,
+	}, nil
+}
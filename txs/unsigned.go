@@ -0,0 +1,222 @@
+// (c) 2021, Stephen Buttolph. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/components/verify"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+)
+
+var (
+	errSubnetAuthUnsupported = errors.New("signing subnet authorizations isn't supported")
+	errOperationsUnsupported = errors.New("signing operations isn't supported")
+	errWrongNumCredentials   = errors.New("wrong number of credentials")
+)
+
+// UnsignedTx is an unsigned transaction of one of the supported chains
+type UnsignedTx interface {
+	// Chain that this transaction should be issued to
+	Chain() Chain
+
+	// Bytes returns the canonical encoding of the unsigned transaction. The
+	// hash of these bytes is what must be signed.
+	Bytes() []byte
+
+	// Tx returns the decoded transaction. It is intended to be used for
+	// displaying the transaction.
+	Tx() interface{}
+
+	// Inputs returns the inputs of the transaction in the order that their
+	// credentials must be provided.
+	Inputs() ([]*avax.TransferableInput, error)
+
+	// Attach returns the signed transaction bytes after attaching one
+	// credential for each of the inputs.
+	Attach(creds []*secp256k1fx.Credential) ([]byte, error)
+}
+
+// Parse decodes [unsignedTxBytes] as an unsigned transaction of [chain]. If
+// [chain] is Unknown, the chain is detected from the transaction's type.
+func Parse(chain Chain, unsignedTxBytes []byte) (UnsignedTx, error) {
+	switch chain {
+	case P:
+		return parsePlatformTx(unsignedTxBytes)
+	case X:
+		return parseAVMTx(unsignedTxBytes)
+	case C:
+		return parseAtomicTx(unsignedTxBytes)
+	case Unknown:
+		// The P-chain and X-chain codecs assign distinct type IDs to their
+		// transactions, so at most one of them will successfully decode the
+		// bytes. The C-chain's type IDs overlap with the X-chain's, so it is
+		// only tried last.
+		pTx, pErr := parsePlatformTx(unsignedTxBytes)
+		if pErr == nil {
+			return pTx, nil
+		}
+		xTx, xErr := parseAVMTx(unsignedTxBytes)
+		if xErr == nil {
+			return xTx, nil
+		}
+		cTx, cErr := parseAtomicTx(unsignedTxBytes)
+		if cErr == nil {
+			return cTx, nil
+		}
+		return nil, fmt.Errorf("couldn't parse as a P-chain (%s), X-chain (%s), or C-chain (%s) transaction", pErr, xErr, cErr)
+	default:
+		return nil, errUnknownChain
+	}
+}
+
+// NewPlatformTx returns the P-chain transaction [tx]
+func NewPlatformTx(tx platformvm.UnsignedTx) (UnsignedTx, error) {
+	unsignedTxBytes, err := platformvm.Codec.Marshal(CodecVersion, &tx)
+	if err != nil {
+		return nil, err
+	}
+	return &platformTx{
+		tx:    tx,
+		bytes: unsignedTxBytes,
+	}, nil
+}
+
+type platformTx struct {
+	tx    platformvm.UnsignedTx
+	bytes []byte
+}
+
+func parsePlatformTx(unsignedTxBytes []byte) (*platformTx, error) {
+	var unsignedTx platformvm.UnsignedTx
+	version, err := platformvm.Codec.Unmarshal(unsignedTxBytes, &unsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	if version != CodecVersion {
+		return nil, fmt.Errorf("expected codec version %d but got %d", CodecVersion, version)
+	}
+	return &platformTx{
+		tx:    unsignedTx,
+		bytes: unsignedTxBytes,
+	}, nil
+}
+
+func (*platformTx) Chain() Chain      { return P }
+func (t *platformTx) Bytes() []byte   { return t.bytes }
+func (t *platformTx) Tx() interface{} { return t.tx }
+
+func (t *platformTx) Inputs() ([]*avax.TransferableInput, error) {
+	switch tx := t.tx.(type) {
+	case *platformvm.UnsignedAddValidatorTx:
+		return tx.Ins, nil
+	case *platformvm.UnsignedAddDelegatorTx:
+		return tx.Ins, nil
+	case *platformvm.UnsignedCreateSubnetTx:
+		return tx.Ins, nil
+	case *platformvm.UnsignedExportTx:
+		return tx.Ins, nil
+	case *platformvm.UnsignedImportTx:
+		ins := make([]*avax.TransferableInput, 0, len(tx.Ins)+len(tx.ImportedInputs))
+		ins = append(ins, tx.Ins...)
+		return append(ins, tx.ImportedInputs...), nil
+	case *platformvm.UnsignedAddSubnetValidatorTx, *platformvm.UnsignedCreateChainTx:
+		return nil, errSubnetAuthUnsupported
+	default:
+		return nil, fmt.Errorf("can't sign transaction of type %T", t.tx)
+	}
+}
+
+func (t *platformTx) Attach(creds []*secp256k1fx.Credential) ([]byte, error) {
+	ins, err := t.Inputs()
+	if err != nil {
+		return nil, err
+	}
+	if len(ins) != len(creds) {
+		return nil, fmt.Errorf("%w: expected %d but got %d", errWrongNumCredentials, len(ins), len(creds))
+	}
+
+	tx := &platformvm.Tx{
+		UnsignedTx: t.tx,
+		Creds:      make([]verify.Verifiable, len(creds)),
+	}
+	for i, cred := range creds {
+		tx.Creds[i] = cred
+	}
+	return platformvm.Codec.Marshal(CodecVersion, tx)
+}
+
+// NewAVMTx returns the X-chain transaction [tx]
+func NewAVMTx(tx avm.UnsignedTx) (UnsignedTx, error) {
+	unsignedTxBytes, err := XCodec.Marshal(CodecVersion, &tx)
+	if err != nil {
+		return nil, err
+	}
+	return &avmTx{
+		tx:    tx,
+		bytes: unsignedTxBytes,
+	}, nil
+}
+
+type avmTx struct {
+	tx    avm.UnsignedTx
+	bytes []byte
+}
+
+func parseAVMTx(unsignedTxBytes []byte) (*avmTx, error) {
+	var unsignedTx avm.UnsignedTx
+	version, err := XCodec.Unmarshal(unsignedTxBytes, &unsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	if version != CodecVersion {
+		return nil, fmt.Errorf("expected codec version %d but got %d", CodecVersion, version)
+	}
+	return &avmTx{
+		tx:    unsignedTx,
+		bytes: unsignedTxBytes,
+	}, nil
+}
+
+func (*avmTx) Chain() Chain      { return X }
+func (t *avmTx) Bytes() []byte   { return t.bytes }
+func (t *avmTx) Tx() interface{} { return t.tx }
+
+func (t *avmTx) Inputs() ([]*avax.TransferableInput, error) {
+	switch tx := t.tx.(type) {
+	case *avm.BaseTx:
+		return tx.Ins, nil
+	case *avm.CreateAssetTx:
+		return tx.Ins, nil
+	case *avm.ExportTx:
+		return tx.Ins, nil
+	case *avm.ImportTx:
+		ins := make([]*avax.TransferableInput, 0, len(tx.Ins)+len(tx.ImportedIns))
+		ins = append(ins, tx.Ins...)
+		return append(ins, tx.ImportedIns...), nil
+	case *avm.OperationTx:
+		return nil, errOperationsUnsupported
+	default:
+		return nil, fmt.Errorf("can't sign transaction of type %T", t.tx)
+	}
+}
+
+func (t *avmTx) Attach(creds []*secp256k1fx.Credential) ([]byte, error) {
+	if numCreds := t.tx.NumCredentials(); numCreds != len(creds) {
+		return nil, fmt.Errorf("%w: expected %d but got %d", errWrongNumCredentials, numCreds, len(creds))
+	}
+
+	tx := &avm.Tx{
+		UnsignedTx: t.tx,
+		Creds:      make([]*avm.FxCredential, len(creds)),
+	}
+	for i, cred := range creds {
+		tx.Creds[i] = &avm.FxCredential{Verifiable: cred}
+	}
+	return XCodec.Marshal(CodecVersion, tx)
+}